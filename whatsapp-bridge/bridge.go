@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.mau.fi/whatsmeow"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// RelayMessage is the transport-agnostic shape passed between WhatsApp and a bridged
+// remote chat platform, following the matterbridge multi-bridge model.
+type RelayMessage struct {
+	ChatJID       string
+	RemoteChannel string
+	Sender        string
+	SenderName    string
+	Content       string
+	MediaPath     string
+	MediaType     string
+	ReplyToID     string
+	Revoked       bool
+}
+
+// Bridger relays messages to and from another chat platform. Implementations are
+// expected to translate RelayMessage into their platform's native send/receive shape.
+type Bridger interface {
+	Send(ctx context.Context, msg RelayMessage) error
+	Receive(ctx context.Context) <-chan RelayMessage
+}
+
+// BridgeConfig maps WhatsApp chat JIDs to remote channel/room IDs on a single target
+// platform, loaded from BRIDGE_CONFIG.
+type BridgeConfig struct {
+	Platform       string            `json:"platform"` // matrix, discord, slack, or webhook
+	WebhookURL     string            `json:"webhook_url"`
+	HomeserverURL  string            `json:"homeserver_url"`
+	AccessToken    string            `json:"access_token"`
+	IncomingSecret string            `json:"incoming_secret"`
+	ChannelMap     map[string]string `json:"channel_map"` // chat JID -> remote channel/room ID
+}
+
+// bridge is the process-wide relay, set up once in main alongside the event bus, or
+// left nil when BRIDGE_CONFIG is unset.
+var bridge Bridger
+
+// NewBridgerFromEnv builds a Bridger from the BRIDGE_CONFIG file, or returns nil if
+// bridging isn't configured.
+func NewBridgerFromEnv(logger waLog.Logger) (Bridger, error) {
+	path := getEnv("BRIDGE_CONFIG", "")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bridge config %s: %v", path, err)
+	}
+
+	var cfg BridgeConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid bridge config JSON: %v", err)
+	}
+
+	switch cfg.Platform {
+	case "matrix", "discord", "slack", "webhook":
+		return newPlatformBridger(cfg, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown bridge platform %q", cfg.Platform)
+	}
+}
+
+// platformBridger relays through a single outbound webhook/API URL per platform, with
+// remote-side replies delivered via registerBridgeIncomingHandler posting onto incoming.
+type platformBridger struct {
+	cfg    BridgeConfig
+	logger waLog.Logger
+
+	incoming chan RelayMessage
+}
+
+func newPlatformBridger(cfg BridgeConfig, logger waLog.Logger) *platformBridger {
+	return &platformBridger{cfg: cfg, logger: logger, incoming: make(chan RelayMessage, 32)}
+}
+
+// remoteChannelFor returns the configured remote channel/room ID for a WhatsApp chat,
+// or "" if the chat isn't bridged.
+func (b *platformBridger) remoteChannelFor(chatJID string) string {
+	return b.cfg.ChannelMap[chatJID]
+}
+
+// chatJIDFor reverse-looks-up the WhatsApp chat JID for a remote channel/room ID.
+func (b *platformBridger) chatJIDFor(remoteChannel string) string {
+	for chatJID, channel := range b.cfg.ChannelMap {
+		if channel == remoteChannel {
+			return chatJID
+		}
+	}
+	return ""
+}
+
+func (b *platformBridger) Send(ctx context.Context, msg RelayMessage) error {
+	remoteChannel := msg.RemoteChannel
+	if remoteChannel == "" {
+		remoteChannel = b.remoteChannelFor(msg.ChatJID)
+	}
+	if remoteChannel == "" {
+		return nil // chat isn't bridged
+	}
+
+	text := fmt.Sprintf("%s: %s", msg.SenderName, msg.Content)
+	if msg.Revoked {
+		text = fmt.Sprintf("%s deleted a message", msg.SenderName)
+	} else if msg.MediaPath != "" {
+		text = fmt.Sprintf("%s: [%s] %s", msg.SenderName, msg.MediaType, msg.Content)
+	}
+
+	var payload []byte
+	var err error
+	switch b.cfg.Platform {
+	case "discord":
+		payload, err = json.Marshal(map[string]string{"content": text})
+	case "slack":
+		payload, err = json.Marshal(map[string]string{"text": text, "channel": remoteChannel})
+	case "matrix":
+		payload, err = json.Marshal(map[string]string{"msgtype": "m.text", "body": text})
+	default: // generic webhook
+		payload, err = json.Marshal(map[string]string{"channel": remoteChannel, "text": text})
+	}
+	if err != nil {
+		return err
+	}
+
+	url := b.cfg.WebhookURL
+	if b.cfg.Platform == "matrix" {
+		url = fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message", b.cfg.HomeserverURL, remoteChannel)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.cfg.Platform == "matrix" && b.cfg.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.cfg.AccessToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bridge send to %s returned status %d", b.cfg.Platform, resp.StatusCode)
+	}
+	return nil
+}
+
+func (b *platformBridger) Receive(ctx context.Context) <-chan RelayMessage {
+	return b.incoming
+}
+
+// registerBridgeIncomingHandler exposes POST /api/bridge/incoming for the remote
+// platform (a Matrix appservice transaction, a Discord/Slack outgoing webhook) to push
+// replies back into WhatsApp. Payload is {"remote_channel", "sender_name", "content"}.
+func registerBridgeIncomingHandler(b *platformBridger, logger waLog.Logger) {
+	http.HandleFunc("/api/bridge/incoming", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if b.cfg.IncomingSecret != "" && r.Header.Get("X-Bridge-Secret") != b.cfg.IncomingSecret {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload struct {
+			RemoteChannel string `json:"remote_channel"`
+			SenderName    string `json:"sender_name"`
+			Content       string `json:"content"`
+			ReplyToID     string `json:"reply_to_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		chatJID := b.chatJIDFor(payload.RemoteChannel)
+		if chatJID == "" {
+			http.Error(w, "Remote channel is not bridged to any chat", http.StatusNotFound)
+			return
+		}
+
+		select {
+		case b.incoming <- RelayMessage{
+			ChatJID:       chatJID,
+			RemoteChannel: payload.RemoteChannel,
+			SenderName:    payload.SenderName,
+			Content:       payload.Content,
+			ReplyToID:     payload.ReplyToID,
+		}:
+		default:
+			logger.Warnf("Bridge incoming queue is full, dropping message from %s", payload.RemoteChannel)
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// runBridgeRelay consumes incoming relay messages from the remote platform and sends
+// them on to WhatsApp via the normal outbound path, so replies round-trip the same way
+// matterbridge's whatsmeow bridge re-injects remote messages.
+func runBridgeRelay(ctx context.Context, client *whatsmeow.Client, messageStore *MessageStore, b Bridger, logger waLog.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-b.Receive(ctx):
+			if !ok {
+				return
+			}
+			if ok, errMsg := sendWhatsAppMessage(client, messageStore, msg.ChatJID, msg.Content, msg.MediaPath, msg.ReplyToID); !ok {
+				logger.Warnf("Failed to relay bridged message into %s: %s", msg.ChatJID, errMsg)
+			}
+		}
+	}
+}
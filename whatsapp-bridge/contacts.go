@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// ContactInfo is the subset of whatsmeow's stored contact info exposed over the API.
+type ContactInfo struct {
+	JID          string `json:"jid"`
+	PushName     string `json:"push_name"`
+	BusinessName string `json:"business_name,omitempty"`
+	FullName     string `json:"full_name,omitempty"`
+}
+
+// registerContactHandlers exposes the locally-known contact list and avatar downloads.
+func registerContactHandlers(client *whatsmeow.Client, logger waLog.Logger) {
+	http.HandleFunc("/api/contacts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		contacts, err := client.Store.Contacts.GetAllContacts(context.Background())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		listings := make([]ContactInfo, 0, len(contacts))
+		for jid, info := range contacts {
+			listings = append(listings, ContactInfo{
+				JID:          jid.String(),
+				PushName:     info.PushName,
+				BusinessName: info.BusinessName,
+				FullName:     info.FullName,
+			})
+		}
+		json.NewEncoder(w).Encode(listings)
+	})
+
+	http.HandleFunc("/api/contacts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/api/contacts/")
+		jid, rest, hasRest := strings.Cut(path, "/")
+		if jid == "" || !hasRest || rest != "avatar" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		contactJID, err := types.ParseJID(jid)
+		if err != nil {
+			http.Error(w, "Invalid contact JID", http.StatusBadRequest)
+			return
+		}
+
+		pic, err := client.GetProfilePictureInfo(context.Background(), contactJID, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if pic == nil {
+			http.Error(w, "No profile picture set", http.StatusNotFound)
+			return
+		}
+
+		resp, err := http.Get(pic.URL)
+		if err != nil {
+			http.Error(w, "Failed to download profile picture", http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		w.Header().Set("Content-Type", "image/jpeg")
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			logger.Warnf("Failed to stream avatar for %s: %v", jid, err)
+		}
+	})
+}
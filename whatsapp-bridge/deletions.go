@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// SoftDeleteMessage blanks a revoked message's content and stamps deleted_at, so a
+// daily summary doesn't quote something the sender retracted, while keeping the row
+// around for thread/reply context.
+func (store *MessageStore) SoftDeleteMessage(id, chatJID string, deletedAt time.Time) error {
+	_, err := store.db.Exec(
+		"UPDATE messages SET content = '', deleted_at = ? WHERE id = ? AND chat_jid = ?",
+		deletedAt, id, chatJID,
+	)
+	return err
+}
+
+// StoreReadReceipt records when a message was read or delivered.
+func (store *MessageStore) StoreReadReceipt(id, chatJID string, readAt time.Time) error {
+	_, err := store.db.Exec(
+		"UPDATE messages SET read_at = ? WHERE id = ? AND chat_jid = ?",
+		readAt, id, chatJID,
+	)
+	return err
+}
+
+// handleRevoke resolves the message referenced by a REVOKE ProtocolMessage and soft-deletes it.
+func handleRevoke(messageStore *MessageStore, chatJID string, protocolMsg *waProto.ProtocolMessage, logger waLog.Logger) {
+	key := protocolMsg.GetKey()
+	if key == nil || key.GetID() == "" {
+		logger.Warnf("Received REVOKE with no message key in %s", chatJID)
+		return
+	}
+
+	revokedID := key.GetID()
+	if err := messageStore.SoftDeleteMessage(revokedID, chatJID, time.Now()); err != nil {
+		logger.Warnf("Failed to soft-delete revoked message %s in %s: %v", revokedID, chatJID, err)
+		return
+	}
+
+	logger.Infof("Message %s in %s was revoked by sender", revokedID, chatJID)
+
+	if bridge != nil {
+		if err := bridge.Send(context.Background(), RelayMessage{ChatJID: chatJID, Revoked: true}); err != nil {
+			logger.Warnf("Failed to relay revoke of %s in %s to bridge: %v", revokedID, chatJID, err)
+		}
+	}
+}
+
+// handleReceipt persists read/delivered receipts onto the referenced messages.
+func handleReceipt(messageStore *MessageStore, evt *events.Receipt, logger waLog.Logger) {
+	if evt.Type != types.ReceiptTypeRead && evt.Type != types.ReceiptTypeDelivered {
+		return
+	}
+
+	chatJID := evt.Chat.String()
+	for _, id := range evt.MessageIDs {
+		if err := messageStore.StoreReadReceipt(id, chatJID, evt.Timestamp); err != nil {
+			logger.Warnf("Failed to store %s receipt for %s in %s: %v", evt.Type, id, chatJID, err)
+		}
+	}
+
+	eventBus.Publish(EventKindReceipt, map[string]interface{}{
+		"chat_jid":    chatJID,
+		"message_ids": evt.MessageIDs,
+		"type":        string(evt.Type),
+		"timestamp":   evt.Timestamp.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// EventEnvelope is the payload shape sent to both webhooks and SSE subscribers, modeled
+// after slidge-whatsapp's EventPayload so external consumers see one event schema
+// regardless of transport.
+type EventEnvelope struct {
+	Kind      string      `json:"kind"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Event kinds dispatched through the EventBus.
+const (
+	EventKindMessage            = "message"
+	EventKindReaction           = "reaction"
+	EventKindReceipt            = "receipt"
+	EventKindPresence           = "presence"
+	EventKindGroup              = "group"
+	EventKindCall               = "call"
+	EventKindTranscriptionReady = "transcription_ready"
+	EventKindConnection         = "connection"
+)
+
+const (
+	webhookMaxRetries  = 4
+	webhookInitialWait = 2 * time.Second
+)
+
+// WebhookConfig maps an event kind to the URLs it should be POSTed to.
+type WebhookConfig struct {
+	Secret string              `json:"secret"`
+	URLs   map[string][]string `json:"urls"`
+}
+
+// EventBus fans out whatsmeow-derived events to outbound webhooks (with HMAC signing
+// and retry/backoff) and to any number of SSE subscribers, so integrators don't have
+// to poll /api/messages/recent.
+type EventBus struct {
+	logger waLog.Logger
+
+	mu          sync.RWMutex
+	webhooks    WebhookConfig
+	subscribers map[chan EventEnvelope]struct{}
+}
+
+// eventBus is the process-wide dispatcher, set up once in main alongside the other
+// global bridge state (auth status, stores).
+var eventBus *EventBus
+
+// NewEventBus builds an EventBus, loading webhook config from WEBHOOK_CONFIG if set.
+func NewEventBus(logger waLog.Logger) *EventBus {
+	bus := &EventBus{
+		logger:      logger,
+		subscribers: make(map[chan EventEnvelope]struct{}),
+	}
+
+	if path := getEnv("WEBHOOK_CONFIG", ""); path != "" {
+		if cfg, err := loadWebhookConfig(path); err != nil {
+			logger.Warnf("Failed to load webhook config %s: %v", path, err)
+		} else {
+			bus.webhooks = cfg
+		}
+	}
+
+	return bus
+}
+
+func loadWebhookConfig(path string) (WebhookConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	var cfg WebhookConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return WebhookConfig{}, fmt.Errorf("invalid webhook config JSON: %v", err)
+	}
+	return cfg, nil
+}
+
+// Publish dispatches an event to webhooks (async, best-effort with retries) and to all
+// currently-subscribed SSE streams.
+func (b *EventBus) Publish(kind string, data interface{}) {
+	envelope := EventEnvelope{Kind: kind, Timestamp: time.Now(), Data: data}
+
+	b.mu.RLock()
+	urls := append([]string(nil), b.webhooks.URLs[kind]...)
+	secret := b.webhooks.Secret
+	subs := make([]chan EventEnvelope, 0, len(b.subscribers))
+	for ch := range b.subscribers {
+		subs = append(subs, ch)
+	}
+	b.mu.RUnlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- envelope:
+		default:
+			// Slow subscriber; drop rather than block the event source.
+		}
+	}
+
+	if len(urls) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		b.logger.Warnf("Failed to marshal event envelope for webhook: %v", err)
+		return
+	}
+
+	for _, url := range urls {
+		go b.postWithRetry(url, secret, payload)
+	}
+}
+
+func (b *EventBus) postWithRetry(url, secret string, payload []byte) {
+	wait := webhookInitialWait
+	for attempt := 1; attempt <= webhookMaxRetries; attempt++ {
+		if err := postWebhook(url, secret, payload); err != nil {
+			b.logger.Warnf("Webhook POST to %s failed (attempt %d/%d): %v", url, attempt, webhookMaxRetries, err)
+			if attempt == webhookMaxRetries {
+				return
+			}
+			time.Sleep(wait)
+			wait *= 2
+			continue
+		}
+		return
+	}
+}
+
+func postWebhook(url, secret string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Subscribe registers a new subscriber (an SSE client, or the WebSocket hub) and returns
+// its event channel plus an unsubscribe function to call once the subscriber goes away.
+func (b *EventBus) Subscribe() (<-chan EventEnvelope, func()) {
+	ch := make(chan EventEnvelope, 32)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// registerEventStreamHandler exposes GET /api/events/stream as a Server-Sent Events feed.
+func registerEventStreamHandler(bus *EventBus) {
+	http.HandleFunc("/api/events/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		events, unsubscribe := bus.Subscribe()
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case evt, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(evt)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Kind, data)
+				flusher.Flush()
+			}
+		}
+	})
+}
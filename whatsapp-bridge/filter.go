@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// FilterConfig is the on-disk shape loaded from FILTER_CONFIG: a blacklist always wins,
+// an allowlist (if non-empty) restricts storage to only those JIDs, and
+// skip_media_download suppresses auto-transcription per chat without dropping the
+// message itself. BlackList/WhiteList are accepted as aliases for blacklist/allowlist so
+// configs written in the capitalized convention (as several community WhatsApp bridges
+// use) load without translation.
+type FilterConfig struct {
+	Blacklist         []string `json:"blacklist"`
+	Allowlist         []string `json:"allowlist"`
+	SkipMediaDownload []string `json:"skip_media_download"`
+}
+
+// UnmarshalJSON merges the capitalized BlackList/WhiteList aliases into Blacklist/Allowlist.
+func (cfg *FilterConfig) UnmarshalJSON(data []byte) error {
+	type alias FilterConfig
+	var aux struct {
+		alias
+		BlackList []string `json:"BlackList"`
+		WhiteList []string `json:"WhiteList"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	*cfg = FilterConfig(aux.alias)
+	cfg.Blacklist = append(cfg.Blacklist, aux.BlackList...)
+	cfg.Allowlist = append(cfg.Allowlist, aux.WhiteList...)
+	return nil
+}
+
+var (
+	filterConfigPath = getEnv("FILTER_CONFIG", "")
+
+	filterMu     sync.RWMutex
+	filterConfig FilterConfig
+)
+
+// loadFilterConfig reads and parses the filter config file, if one is configured.
+func loadFilterConfig(logger waLog.Logger) {
+	if filterConfigPath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(filterConfigPath)
+	if err != nil {
+		logger.Warnf("Failed to read filter config %s: %v", filterConfigPath, err)
+		return
+	}
+
+	var cfg FilterConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		logger.Warnf("Failed to parse filter config %s: %v", filterConfigPath, err)
+		return
+	}
+
+	filterMu.Lock()
+	filterConfig = cfg
+	filterMu.Unlock()
+}
+
+// watchFilterConfigReload reloads the filter config whenever the process receives SIGHUP,
+// so rules can be edited without a restart.
+func watchFilterConfigReload(logger waLog.Logger) {
+	if filterConfigPath == "" {
+		return
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		for range sighup {
+			logger.Infof("Reloading filter config from %s", filterConfigPath)
+			loadFilterConfig(logger)
+		}
+	}()
+}
+
+// watchFilterConfigFile reloads the filter config on external edits (e.g. a config
+// management tool rewriting the file), so operators don't need to send SIGHUP manually.
+func watchFilterConfigFile(logger waLog.Logger) {
+	if filterConfigPath == "" {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Warnf("Failed to start filter config watcher: %v", err)
+		return
+	}
+	if err := watcher.Add(filterConfigPath); err != nil {
+		logger.Warnf("Failed to watch filter config %s: %v", filterConfigPath, err)
+		watcher.Close()
+		return
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					logger.Infof("Filter config %s changed, reloading", filterConfigPath)
+					loadFilterConfig(logger)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warnf("Filter config watcher error: %v", err)
+			}
+		}
+	}()
+}
+
+func containsJID(list []string, jid string) bool {
+	for _, candidate := range list {
+		if candidate == jid {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldStoreMessage reports whether a message from senderJID in chatJID should be
+// persisted at all: the blacklist always wins, and a non-empty allowlist makes storage
+// opt-in.
+func shouldStoreMessage(senderJID, chatJID string) bool {
+	filterMu.RLock()
+	defer filterMu.RUnlock()
+
+	if containsJID(filterConfig.Blacklist, senderJID) || containsJID(filterConfig.Blacklist, chatJID) {
+		return false
+	}
+
+	if len(filterConfig.Allowlist) == 0 {
+		return true
+	}
+
+	return containsJID(filterConfig.Allowlist, senderJID) || containsJID(filterConfig.Allowlist, chatJID)
+}
+
+// shouldTranscribeChat reports whether auto-transcription is allowed for chatJID.
+func shouldTranscribeChat(chatJID string) bool {
+	filterMu.RLock()
+	defer filterMu.RUnlock()
+	return !containsJID(filterConfig.SkipMediaDownload, chatJID)
+}
+
+// registerFilterHandlers exposes GET/PUT /api/filter (and the /api/filters alias) to view
+// and mutate the blacklist/allowlist rules at runtime, plus /api/filters/{jid}/mute to
+// toggle a single chat's muted state.
+func registerFilterHandlers(messageStore *MessageStore) {
+	handleFilterConfig := func(w http.ResponseWriter, r *http.Request) {
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodGet:
+			filterMu.RLock()
+			cfg := filterConfig
+			filterMu.RUnlock()
+			json.NewEncoder(w).Encode(cfg)
+		case http.MethodPut:
+			var cfg FilterConfig
+			if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+				return
+			}
+			filterMu.Lock()
+			filterConfig = cfg
+			filterMu.Unlock()
+			json.NewEncoder(w).Encode(cfg)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+	http.HandleFunc("/api/filter", handleFilterConfig)
+	http.HandleFunc("/api/filters", handleFilterConfig)
+
+	http.HandleFunc("/api/filters/", func(w http.ResponseWriter, r *http.Request) {
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/api/filters/")
+		jid, rest, hasRest := strings.Cut(path, "/")
+		if jid == "" || !hasRest || rest != "mute" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodGet:
+			muted, err := messageStore.IsChatMuted(jid)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]bool{"muted": muted})
+		case http.MethodPut:
+			var body struct {
+				Muted bool `json:"muted"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+				return
+			}
+			if err := messageStore.SetChatMuted(jid, body.Muted); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]bool{"muted": body.Muted})
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
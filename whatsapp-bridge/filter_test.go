@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFilterConfigUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want FilterConfig
+	}{
+		{
+			name: "lowercase only",
+			json: `{"blacklist": ["a@s.whatsapp.net"], "allowlist": ["b@s.whatsapp.net"]}`,
+			want: FilterConfig{Blacklist: []string{"a@s.whatsapp.net"}, Allowlist: []string{"b@s.whatsapp.net"}},
+		},
+		{
+			name: "capitalized aliases only",
+			json: `{"BlackList": ["a@s.whatsapp.net"], "WhiteList": ["b@s.whatsapp.net"]}`,
+			want: FilterConfig{Blacklist: []string{"a@s.whatsapp.net"}, Allowlist: []string{"b@s.whatsapp.net"}},
+		},
+		{
+			name: "both shapes merge",
+			json: `{"blacklist": ["a@s.whatsapp.net"], "BlackList": ["c@s.whatsapp.net"], "allowlist": ["b@s.whatsapp.net"], "WhiteList": ["d@s.whatsapp.net"]}`,
+			want: FilterConfig{
+				Blacklist: []string{"a@s.whatsapp.net", "c@s.whatsapp.net"},
+				Allowlist: []string{"b@s.whatsapp.net", "d@s.whatsapp.net"},
+			},
+		},
+		{
+			name: "skip_media_download passes through untouched",
+			json: `{"skip_media_download": ["e@s.whatsapp.net"]}`,
+			want: FilterConfig{SkipMediaDownload: []string{"e@s.whatsapp.net"}},
+		},
+		{
+			name: "empty object",
+			json: `{}`,
+			want: FilterConfig{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got FilterConfig
+			if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldStoreMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       FilterConfig
+		senderJID string
+		chatJID   string
+		want      bool
+	}{
+		{
+			name:      "no rules allows everything",
+			cfg:       FilterConfig{},
+			senderJID: "a@s.whatsapp.net",
+			chatJID:   "chat@g.us",
+			want:      true,
+		},
+		{
+			name:      "blacklisted sender is blocked",
+			cfg:       FilterConfig{Blacklist: []string{"a@s.whatsapp.net"}},
+			senderJID: "a@s.whatsapp.net",
+			chatJID:   "chat@g.us",
+			want:      false,
+		},
+		{
+			name:      "blacklisted chat is blocked",
+			cfg:       FilterConfig{Blacklist: []string{"chat@g.us"}},
+			senderJID: "a@s.whatsapp.net",
+			chatJID:   "chat@g.us",
+			want:      false,
+		},
+		{
+			name:      "allowlist restricts to listed sender",
+			cfg:       FilterConfig{Allowlist: []string{"a@s.whatsapp.net"}},
+			senderJID: "a@s.whatsapp.net",
+			chatJID:   "chat@g.us",
+			want:      true,
+		},
+		{
+			name:      "allowlist blocks unlisted sender and chat",
+			cfg:       FilterConfig{Allowlist: []string{"a@s.whatsapp.net"}},
+			senderJID: "z@s.whatsapp.net",
+			chatJID:   "other@g.us",
+			want:      false,
+		},
+		{
+			name:      "blacklist wins over allowlist",
+			cfg:       FilterConfig{Blacklist: []string{"a@s.whatsapp.net"}, Allowlist: []string{"a@s.whatsapp.net"}},
+			senderJID: "a@s.whatsapp.net",
+			chatJID:   "chat@g.us",
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filterMu.Lock()
+			filterConfig = tt.cfg
+			filterMu.Unlock()
+
+			if got := shouldStoreMessage(tt.senderJID, tt.chatJID); got != tt.want {
+				t.Errorf("shouldStoreMessage(%q, %q) = %v, want %v", tt.senderJID, tt.chatJID, got, tt.want)
+			}
+		})
+	}
+}
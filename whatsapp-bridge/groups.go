@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// GroupParticipant is a single participant entry in a group's metadata.
+type GroupParticipant struct {
+	JID          string `json:"jid"`
+	IsAdmin      bool   `json:"is_admin"`
+	IsSuperAdmin bool   `json:"is_super_admin"`
+}
+
+// GroupMetadata mirrors the subset of whatsmeow's GroupInfo we persist.
+type GroupMetadata struct {
+	JID          string             `json:"jid"`
+	Subject      string             `json:"subject"`
+	Owner        string             `json:"owner"`
+	Participants []GroupParticipant `json:"participants"`
+	LastSync     time.Time          `json:"last_sync"`
+}
+
+// StoreGroup upserts a group's metadata, serializing participants as JSON.
+func (store *MessageStore) StoreGroup(jid, subject, owner string, participants []GroupParticipant, lastSync time.Time) error {
+	participantsJSON, err := json.Marshal(participants)
+	if err != nil {
+		return fmt.Errorf("failed to marshal participants: %v", err)
+	}
+
+	_, err = store.db.Exec(
+		"INSERT OR REPLACE INTO groups (jid, subject, owner, participants, last_sync) VALUES (?, ?, ?, ?, ?)",
+		jid, subject, owner, string(participantsJSON), lastSync,
+	)
+	return err
+}
+
+// GetGroup returns the stored metadata for a single group.
+func (store *MessageStore) GetGroup(jid string) (*GroupMetadata, error) {
+	var meta GroupMetadata
+	var participantsJSON string
+	err := store.db.QueryRow(
+		"SELECT jid, subject, owner, participants, last_sync FROM groups WHERE jid = ?",
+		jid,
+	).Scan(&meta.JID, &meta.Subject, &meta.Owner, &participantsJSON, &meta.LastSync)
+	if err != nil {
+		return nil, err
+	}
+
+	if participantsJSON != "" {
+		if err := json.Unmarshal([]byte(participantsJSON), &meta.Participants); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal participants: %v", err)
+		}
+	}
+	return &meta, nil
+}
+
+// GetGroups returns metadata for every group we know about.
+func (store *MessageStore) GetGroups() ([]GroupMetadata, error) {
+	rows, err := store.db.Query("SELECT jid, subject, owner, participants, last_sync FROM groups ORDER BY subject ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []GroupMetadata
+	for rows.Next() {
+		var meta GroupMetadata
+		var participantsJSON string
+		if err := rows.Scan(&meta.JID, &meta.Subject, &meta.Owner, &participantsJSON, &meta.LastSync); err != nil {
+			return nil, err
+		}
+		if participantsJSON != "" {
+			if err := json.Unmarshal([]byte(participantsJSON), &meta.Participants); err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, meta)
+	}
+	return groups, nil
+}
+
+// syncGroup fetches fresh info for a single group JID and stores it.
+func syncGroup(client *whatsmeow.Client, messageStore *MessageStore, jid types.JID, logger waLog.Logger) {
+	info, err := client.GetGroupInfo(context.Background(), jid)
+	if err != nil {
+		logger.Warnf("Failed to fetch group info for %s: %v", jid, err)
+		return
+	}
+
+	participants := make([]GroupParticipant, 0, len(info.Participants))
+	for _, p := range info.Participants {
+		participants = append(participants, GroupParticipant{
+			JID:          p.JID.String(),
+			IsAdmin:      p.IsAdmin,
+			IsSuperAdmin: p.IsSuperAdmin,
+		})
+	}
+
+	if err := messageStore.StoreGroup(jid.String(), info.Name, info.OwnerJID.String(), participants, time.Now()); err != nil {
+		logger.Warnf("Failed to store group %s: %v", jid, err)
+		return
+	}
+
+	eventBus.Publish(EventKindGroup, GroupMetadata{
+		JID:          jid.String(),
+		Subject:      info.Name,
+		Owner:        info.OwnerJID.String(),
+		Participants: participants,
+		LastSync:     time.Now(),
+	})
+}
+
+// syncAllGroups populates the groups table from the joined-groups list. Called on startup.
+func syncAllGroups(client *whatsmeow.Client, messageStore *MessageStore, logger waLog.Logger) {
+	groups, err := client.GetJoinedGroups(context.Background())
+	if err != nil {
+		logger.Warnf("Failed to fetch joined groups: %v", err)
+		return
+	}
+
+	for _, info := range groups {
+		participants := make([]GroupParticipant, 0, len(info.Participants))
+		for _, p := range info.Participants {
+			participants = append(participants, GroupParticipant{
+				JID:          p.JID.String(),
+				IsAdmin:      p.IsAdmin,
+				IsSuperAdmin: p.IsSuperAdmin,
+			})
+		}
+		if err := messageStore.StoreGroup(info.JID.String(), info.Name, info.OwnerJID.String(), participants, time.Now()); err != nil {
+			logger.Warnf("Failed to store group %s: %v", info.JID, err)
+		}
+	}
+
+	logger.Infof("Synced %d joined groups", len(groups))
+}
+
+// handleGroupInfoChange reacts to events.GroupInfo by re-syncing the affected group.
+func handleGroupInfoChange(client *whatsmeow.Client, messageStore *MessageStore, evt *events.GroupInfo, logger waLog.Logger) {
+	syncGroup(client, messageStore, evt.JID, logger)
+}
+
+// handleJoinedGroup reacts to events.JoinedGroup by syncing the newly joined group.
+func handleJoinedGroup(client *whatsmeow.Client, messageStore *MessageStore, evt *events.JoinedGroup, logger waLog.Logger) {
+	syncGroup(client, messageStore, evt.JID, logger)
+}
+
+// StoreGroupMessageMeta records the per-message group context (sender push name,
+// group subject, and participant count at the time the message was sent) so a
+// daily summary can cite "who said what in which group" after the group's
+// subject or membership has since changed.
+func (store *MessageStore) StoreGroupMessageMeta(id, chatJID, pushName, subject string, participantCount int) error {
+	_, err := store.db.Exec(
+		"UPDATE messages SET sender_push_name = ?, group_subject = ?, group_participant_count = ? WHERE id = ? AND chat_jid = ?",
+		pushName, subject, participantCount, id, chatJID,
+	)
+	return err
+}
+
+// groupParticipantsRequest is the body for POST /api/groups/{jid}/participants.
+type groupParticipantsRequest struct {
+	Action       string   `json:"action"` // add, remove, promote, demote
+	Participants []string `json:"participants"`
+}
+
+// createGroupRequest is the body for POST /api/groups/create.
+type createGroupRequest struct {
+	Name         string   `json:"name"`
+	Participants []string `json:"participants"`
+}
+
+func participantChangeForAction(action string) (whatsmeow.ParticipantChange, bool) {
+	switch action {
+	case "add":
+		return whatsmeow.ParticipantChangeAdd, true
+	case "remove":
+		return whatsmeow.ParticipantChangeRemove, true
+	case "promote":
+		return whatsmeow.ParticipantChangePromote, true
+	case "demote":
+		return whatsmeow.ParticipantChangeDemote, true
+	default:
+		return "", false
+	}
+}
+
+// registerGroupHandlers wires up group-related endpoints on the REST server.
+func registerGroupHandlers(client *whatsmeow.Client, messageStore *MessageStore, logger waLog.Logger) {
+	// List all known groups
+	http.HandleFunc("/api/groups", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		groups, err := messageStore.GetGroups()
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		type groupListing struct {
+			GroupMetadata
+			ParticipantCount int `json:"participant_count"`
+		}
+		listings := make([]groupListing, 0, len(groups))
+		for _, group := range groups {
+			listings = append(listings, groupListing{GroupMetadata: group, ParticipantCount: len(group.Participants)})
+		}
+		json.NewEncoder(w).Encode(listings)
+	})
+
+	// POST /api/groups/create
+	http.HandleFunc("/api/groups/create", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var req createGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+			return
+		}
+
+		participants := make([]types.JID, 0, len(req.Participants))
+		for _, p := range req.Participants {
+			jid, err := types.ParseJID(p)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid participant JID %q: %v", p, err)})
+				return
+			}
+			participants = append(participants, jid)
+		}
+
+		info, err := client.CreateGroup(context.Background(), whatsmeow.ReqCreateGroup{
+			Name:         req.Name,
+			Participants: participants,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		syncGroup(client, messageStore, info.JID, logger)
+		group, err := messageStore.GetGroup(info.JID.String())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(group)
+	})
+
+	// /api/groups/{jid}, /api/groups/{jid}/participants, /api/groups/{jid}/leave, /api/groups/{jid}/invite
+	http.HandleFunc("/api/groups/", func(w http.ResponseWriter, r *http.Request) {
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/api/groups/")
+		jid, rest, hasRest := strings.Cut(path, "/")
+		if jid == "" {
+			http.Error(w, "Group JID is required", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		groupJID, err := types.ParseJID(jid)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid group JID: %v", err)})
+			return
+		}
+
+		if hasRest && rest == "leave" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := client.LeaveGroup(context.Background(), groupJID); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]bool{"success": true})
+			return
+		}
+
+		if hasRest && rest == "invite" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			reset := r.URL.Query().Get("reset") == "true"
+			link, err := client.GetGroupInviteLink(context.Background(), groupJID, reset)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"invite_link": link})
+			return
+		}
+
+		if hasRest && rest == "participants" && r.Method == http.MethodPost {
+			var req groupParticipantsRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+				return
+			}
+
+			change, ok := participantChangeForAction(req.Action)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("unknown action %q", req.Action)})
+				return
+			}
+
+			participantJIDs := make([]types.JID, 0, len(req.Participants))
+			for _, p := range req.Participants {
+				pJID, err := types.ParseJID(p)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid participant JID %q: %v", p, err)})
+					return
+				}
+				participantJIDs = append(participantJIDs, pJID)
+			}
+
+			if _, err := client.UpdateGroupParticipants(context.Background(), groupJID, participantJIDs, change); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+
+			syncGroup(client, messageStore, groupJID, logger)
+			group, err := messageStore.GetGroup(jid)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(group.Participants)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		group, err := messageStore.GetGroup(jid)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "group not found"})
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		if hasRest && rest == "participants" {
+			json.NewEncoder(w).Encode(group.Participants)
+			return
+		}
+
+		json.NewEncoder(w).Encode(group)
+	})
+}
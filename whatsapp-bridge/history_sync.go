@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// historySyncStatusJID is where history sync requests are sent, matching requestHistorySync.
+const historySyncStatusJID = "status"
+
+// historySyncManager is the process-wide on-demand backfill coordinator, set up once in
+// startRESTServer alongside the other global bridge state (event bus, responders).
+var historySyncManager *HistorySyncManager
+
+// HistorySyncProgress reports how an on-demand per-chat backfill is advancing, so callers
+// don't have to poll GetMessages to find out whether a deep sync finished.
+type HistorySyncProgress struct {
+	Chat          string    `json:"chat"`
+	Fetched       int       `json:"fetched"`
+	Total         int       `json:"total"`
+	LastTimestamp time.Time `json:"last_timestamp"`
+	Done          bool      `json:"done"`
+}
+
+// historySyncRequest tracks one chat's outstanding on-demand backfill.
+type historySyncRequest struct {
+	want    int
+	fetched int
+	before  *time.Time              // only accept pages older than this, for RequestRange
+	oldest  *waProto.WebMessageInfo // raw history-sync message anchoring the next BuildHistorySyncRequest page
+}
+
+// HistorySyncManager layers on-demand, per-chat deep backfill on top of the one-shot
+// initial dump handleHistorySync already stores: callers can request a specific chat's
+// oldest N messages, observe progress as pages arrive, and resume after a restart from a
+// persisted cursor instead of re-running the server's initial 100-message dump.
+type HistorySyncManager struct {
+	client       *whatsmeow.Client
+	messageStore *MessageStore
+	logger       waLog.Logger
+
+	Progress chan HistorySyncProgress
+
+	mu      sync.Mutex
+	pending map[string]*historySyncRequest // keyed by chat JID string
+}
+
+// createHistorySyncCursorTable persists where each chat's on-demand backfill left off.
+func createHistorySyncCursorTable(store *MessageStore) error {
+	_, err := store.db.Exec(`
+		CREATE TABLE IF NOT EXISTS history_sync_cursors (
+			chat_jid TEXT PRIMARY KEY,
+			oldest_message_id TEXT,
+			oldest_timestamp TIMESTAMP,
+			fetched INTEGER NOT NULL DEFAULT 0,
+			total INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP
+		);
+	`)
+	return err
+}
+
+// NewHistorySyncManager creates the manager and its cursor table.
+func NewHistorySyncManager(client *whatsmeow.Client, messageStore *MessageStore, logger waLog.Logger) (*HistorySyncManager, error) {
+	if err := createHistorySyncCursorTable(messageStore); err != nil {
+		return nil, fmt.Errorf("failed to create history sync cursor table: %v", err)
+	}
+
+	return &HistorySyncManager{
+		client:       client,
+		messageStore: messageStore,
+		logger:       logger,
+		Progress:     make(chan HistorySyncProgress, 32),
+		pending:      make(map[string]*historySyncRequest),
+	}, nil
+}
+
+// Cursor returns the persisted backfill progress for a chat, if any request was ever made.
+func (m *HistorySyncManager) Cursor(chatJID string) (oldestMessageID string, oldestTimestamp time.Time, fetched, total int, err error) {
+	row := m.messageStore.db.QueryRow(
+		"SELECT oldest_message_id, oldest_timestamp, fetched, total FROM history_sync_cursors WHERE chat_jid = ?", chatJID,
+	)
+	if err = row.Scan(&oldestMessageID, &oldestTimestamp, &fetched, &total); err == sql.ErrNoRows {
+		return "", time.Time{}, 0, 0, nil
+	}
+	return
+}
+
+func (m *HistorySyncManager) saveCursor(chatJID, oldestMessageID string, oldestTimestamp time.Time, fetched, total int) error {
+	_, err := m.messageStore.db.Exec(
+		`INSERT INTO history_sync_cursors (chat_jid, oldest_message_id, oldest_timestamp, fetched, total, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(chat_jid) DO UPDATE SET oldest_message_id = excluded.oldest_message_id,
+			oldest_timestamp = excluded.oldest_timestamp, fetched = excluded.fetched,
+			total = excluded.total, updated_at = excluded.updated_at`,
+		chatJID, oldestMessageID, oldestTimestamp, fetched, total, time.Now(),
+	)
+	return err
+}
+
+// messageExists reports whether a message is already stored, used to count only the new
+// messages of a backfilled page before it is stored, since StoreMessage's INSERT OR REPLACE
+// accepts duplicates silently.
+func (store *MessageStore) messageExists(id, chatJID string) (bool, error) {
+	var exists int
+	err := store.db.QueryRow("SELECT 1 FROM messages WHERE id = ? AND chat_jid = ?", id, chatJID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RequestOldest asks the server for the oldest `count` messages in chatJID, resuming from
+// whatever cursor was left over from a previous run rather than starting the page anchor over.
+func (m *HistorySyncManager) RequestOldest(chatJID types.JID, count int) error {
+	return m.request(chatJID, count, nil)
+}
+
+// RequestRange asks the server for up to `count` messages in chatJID older than `before`.
+func (m *HistorySyncManager) RequestRange(chatJID types.JID, before time.Time, count int) error {
+	return m.request(chatJID, count, &before)
+}
+
+func (m *HistorySyncManager) request(chatJID types.JID, count int, before *time.Time) error {
+	if m.client == nil || !reconnectSupervisor.CanSend() || m.client.Store.ID == nil {
+		return fmt.Errorf("client is not ready")
+	}
+
+	chatStr := chatJID.String()
+
+	req := &historySyncRequest{want: count, before: before}
+	if before == nil {
+		// Anchor on the persisted cursor, so paging continues below the oldest message a
+		// previous run already fetched.
+		if oldestID, oldestTs, _, _, err := m.Cursor(chatStr); err == nil && oldestID != "" {
+			ts := uint64(oldestTs.Unix())
+			req.oldest = &waProto.WebMessageInfo{Key: &waProto.MessageKey{ID: &oldestID}, MessageTimestamp: &ts}
+		}
+	}
+
+	m.mu.Lock()
+	if _, exists := m.pending[chatStr]; exists {
+		m.mu.Unlock()
+		return fmt.Errorf("a history sync is already in progress for %s", chatStr)
+	}
+	m.pending[chatStr] = req
+	m.mu.Unlock()
+
+	return m.requestNextPage(chatJID, req)
+}
+
+// anchorFromWebMessageInfo reconstructs the *types.MessageInfo BuildHistorySyncRequest
+// expects from the raw *waProto.WebMessageInfo a history-sync page gives us; the two don't
+// share a type, so the ID/timestamp fields have to be copied across by hand.
+func anchorFromWebMessageInfo(oldest *waProto.WebMessageInfo) *types.MessageInfo {
+	if oldest == nil || oldest.Key == nil || oldest.Key.ID == nil {
+		return nil
+	}
+
+	ts := time.Time{}
+	if rawTs := oldest.GetMessageTimestamp(); rawTs != 0 {
+		ts = time.Unix(int64(rawTs), 0)
+	}
+
+	return &types.MessageInfo{
+		ID:        *oldest.Key.ID,
+		Timestamp: ts,
+	}
+}
+
+func (m *HistorySyncManager) requestNextPage(chatJID types.JID, req *historySyncRequest) error {
+	historyMsg := m.client.BuildHistorySyncRequest(anchorFromWebMessageInfo(req.oldest), req.want-req.fetched)
+	if historyMsg == nil {
+		m.finish(chatJID)
+		return fmt.Errorf("failed to build history sync request for %s", chatJID)
+	}
+
+	_, err := m.client.SendMessage(context.Background(), types.JID{Server: "s.whatsapp.net", User: historySyncStatusJID}, historyMsg)
+	if err != nil {
+		m.finish(chatJID)
+		return fmt.Errorf("failed to send history sync request: %v", err)
+	}
+	return nil
+}
+
+// Ingest processes one events.HistorySync payload. For any chat with an outstanding
+// on-demand request it first dedupes the new page against the store, advances that chat's
+// cursor, emits a HistorySyncProgress event, and re-issues BuildHistorySyncRequest anchored
+// on the oldest message seen so far until the requested count is reached or the server
+// returns nothing new. It then stores the payload via the existing handleHistorySync path.
+func (m *HistorySyncManager) Ingest(client *whatsmeow.Client, historySync *events.HistorySync, logger waLog.Logger) {
+	for _, conversation := range historySync.Data.Conversations {
+		if conversation.ID == nil {
+			continue
+		}
+		chatJIDStr := *conversation.ID
+
+		m.mu.Lock()
+		req, ok := m.pending[chatJIDStr]
+		m.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		jid, err := types.ParseJID(chatJIDStr)
+		if err != nil {
+			continue
+		}
+
+		newCount := 0
+		var oldestTimestamp time.Time
+		var oldestID string
+		for _, msg := range conversation.Messages {
+			if msg == nil || msg.Message == nil || msg.Message.Key == nil || msg.Message.Key.ID == nil {
+				continue
+			}
+
+			ts := time.Time{}
+			if rawTs := msg.Message.GetMessageTimestamp(); rawTs != 0 {
+				ts = time.Unix(int64(rawTs), 0)
+			}
+			if req.before != nil && !ts.Before(*req.before) {
+				continue // outside the requested window
+			}
+			if exists, _ := m.messageStore.messageExists(*msg.Message.Key.ID, chatJIDStr); exists {
+				continue
+			}
+
+			newCount++
+			if oldestTimestamp.IsZero() || ts.Before(oldestTimestamp) {
+				oldestTimestamp = ts
+				oldestID = *msg.Message.Key.ID
+				req.oldest = msg.Message
+			}
+		}
+
+		req.fetched += newCount
+		if newCount > 0 {
+			if err := m.saveCursor(chatJIDStr, oldestID, oldestTimestamp, req.fetched, req.want); err != nil {
+				logger.Warnf("Failed to save history sync cursor for %s: %v", chatJIDStr, err)
+			}
+		}
+
+		done := newCount == 0 || req.fetched >= req.want
+		m.emitProgress(HistorySyncProgress{Chat: chatJIDStr, Fetched: req.fetched, Total: req.want, LastTimestamp: oldestTimestamp, Done: done})
+
+		if done {
+			m.finish(jid)
+			continue
+		}
+
+		if err := m.requestNextPage(jid, req); err != nil {
+			logger.Warnf("Failed to request next history sync page for %s: %v", chatJIDStr, err)
+			m.finish(jid)
+		}
+	}
+
+	handleHistorySync(client, m.messageStore, historySync, logger)
+}
+
+func (m *HistorySyncManager) finish(chatJID types.JID) {
+	m.mu.Lock()
+	delete(m.pending, chatJID.String())
+	m.mu.Unlock()
+}
+
+func (m *HistorySyncManager) emitProgress(p HistorySyncProgress) {
+	select {
+	case m.Progress <- p:
+	default:
+		// Slow consumer; drop rather than block history sync event processing.
+	}
+}
+
+// historySyncRequestBody is the JSON payload accepted by POST /api/history/sync.
+type historySyncRequestBody struct {
+	ChatJID string     `json:"chat_jid"`
+	Count   int        `json:"count"`
+	Before  *time.Time `json:"before,omitempty"`
+}
+
+// registerHistorySyncHandlers exposes POST /api/history/sync to kick off an on-demand
+// backfill and GET /api/history/sync/{chat_jid} to check its persisted cursor.
+func registerHistorySyncHandlers(manager *HistorySyncManager) {
+	http.HandleFunc("/api/history/sync", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var body historySyncRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+			return
+		}
+
+		jid, err := types.ParseJID(body.ChatJID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid chat JID %q: %v", body.ChatJID, err)})
+			return
+		}
+		if body.Count <= 0 {
+			body.Count = 100
+		}
+
+		if body.Before != nil {
+			err = manager.RequestRange(jid, *body.Before, body.Count)
+		} else {
+			err = manager.RequestOldest(jid, body.Count)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"status": "requested"})
+	})
+
+	http.HandleFunc("/api/history/sync/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		chatJID := r.URL.Path[len("/api/history/sync/"):]
+		oldestMessageID, oldestTimestamp, fetched, total, err := manager.Cursor(chatJID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"chat_jid":          chatJID,
+			"oldest_message_id": oldestMessageID,
+			"oldest_timestamp":  oldestTimestamp,
+			"fetched":           fetched,
+			"total":             total,
+		})
+	})
+}
@@ -1,17 +1,14 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"reflect"
@@ -58,10 +55,14 @@ var (
 
 func setAuthState(status, qr, pair string) {
 	authMu.Lock()
-	defer authMu.Unlock()
 	authStatus = status
 	currentQR = qr
 	pairCode = pair
+	authMu.Unlock()
+
+	if eventBus != nil {
+		eventBus.Publish(EventKindConnection, map[string]string{"status": status, "qr_code": qr, "pair_code": pair})
+	}
 }
 
 func getAuthState() (string, string, string) {
@@ -230,13 +231,20 @@ type MessageStore struct {
 
 // Initialize message store
 func NewMessageStore() (*MessageStore, error) {
+	return NewMessageStoreAt(storeDir)
+}
+
+// NewMessageStoreAt initializes a message store rooted at dir instead of the global
+// storeDir, so each device managed by a SessionManager can keep its own messages.db
+// without clobbering another device's history.
+func NewMessageStoreAt(dir string) (*MessageStore, error) {
 	// Create directory for database if it doesn't exist
-	if err := os.MkdirAll(storeDir, 0755); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create store directory: %v", err)
 	}
 
 	// Open SQLite database for messages
-	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s/messages.db?_foreign_keys=on", storeDir))
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s/messages.db?_foreign_keys=on", dir))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open message database: %v", err)
 	}
@@ -267,6 +275,14 @@ func NewMessageStore() (*MessageStore, error) {
 			PRIMARY KEY (id, chat_jid),
 			FOREIGN KEY (chat_jid) REFERENCES chats(jid)
 		);
+
+		CREATE TABLE IF NOT EXISTS groups (
+			jid TEXT PRIMARY KEY,
+			subject TEXT,
+			owner TEXT,
+			participants TEXT,
+			last_sync TIMESTAMP
+		);
 	`)
 	if err != nil {
 		db.Close()
@@ -282,6 +298,26 @@ func NewMessageStore() (*MessageStore, error) {
 		}
 		err = nil
 	}
+
+	// Migration: add per-message group context columns for existing databases
+	for _, stmt := range []string{
+		`ALTER TABLE messages ADD COLUMN sender_push_name TEXT`,
+		`ALTER TABLE messages ADD COLUMN group_subject TEXT`,
+		`ALTER TABLE messages ADD COLUMN group_participant_count INTEGER`,
+		`ALTER TABLE messages ADD COLUMN reply_to_id TEXT`,
+		`ALTER TABLE messages ADD COLUMN reply_to_sender TEXT`,
+		`ALTER TABLE messages ADD COLUMN quoted_content TEXT`,
+		`ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMP`,
+		`ALTER TABLE messages ADD COLUMN read_at TIMESTAMP`,
+		`ALTER TABLE chats ADD COLUMN muted BOOLEAN DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN reactions_json TEXT`,
+		`ALTER TABLE messages ADD COLUMN edited_at TIMESTAMP`,
+	} {
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			// Not a duplicate column error, but still not fatal
+		}
+	}
+
 	return &MessageStore{db: db}, nil
 }
 
@@ -299,6 +335,24 @@ func (store *MessageStore) StoreChat(jid, name string, lastMessageTime time.Time
 	return err
 }
 
+// SetChatMuted flips whether a chat is muted; handleMessage skips muted chats (after the
+// shouldStoreMessage check) so filtering can be done per-chat at runtime instead of only
+// via the blacklist file.
+func (store *MessageStore) SetChatMuted(jid string, muted bool) error {
+	_, err := store.db.Exec("UPDATE chats SET muted = ? WHERE jid = ?", muted, jid)
+	return err
+}
+
+// IsChatMuted reports whether a chat is muted. Unknown chats are treated as unmuted.
+func (store *MessageStore) IsChatMuted(jid string) (bool, error) {
+	var muted bool
+	err := store.db.QueryRow("SELECT muted FROM chats WHERE jid = ?", jid).Scan(&muted)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return muted, err
+}
+
 // Store a message in the database
 func (store *MessageStore) StoreMessage(id, chatJID, sender, content string, timestamp time.Time, isFromMe bool,
 	mediaType, filename, url string, mediaKey, fileSHA256, fileEncSHA256 []byte, fileLength uint64) error {
@@ -373,6 +427,26 @@ func (store *MessageStore) StoreTranscription(id, chatJID, transcription string)
 	return err
 }
 
+// StoreReplyInfo records the reply/quote linkage for a message so the thread can be
+// reconstructed later without re-fetching the quoted message from WhatsApp.
+func (store *MessageStore) StoreReplyInfo(id, chatJID, replyToID, replyToSender, quotedContent string) error {
+	_, err := store.db.Exec(
+		"UPDATE messages SET reply_to_id = ?, reply_to_sender = ?, quoted_content = ? WHERE id = ? AND chat_jid = ?",
+		replyToID, replyToSender, quotedContent, id, chatJID,
+	)
+	return err
+}
+
+// GetMessageForQuote looks up the sender, content and media type of a message by ID,
+// used to build outgoing ContextInfo when replying.
+func (store *MessageStore) GetMessageForQuote(id, chatJID string) (sender, content, mediaType string, err error) {
+	err = store.db.QueryRow(
+		"SELECT sender, COALESCE(content, ''), COALESCE(media_type, '') FROM messages WHERE id = ? AND chat_jid = ?",
+		id, chatJID,
+	).Scan(&sender, &content, &mediaType)
+	return sender, content, mediaType, err
+}
+
 // RecentMessage represents a message returned by the /api/messages/recent endpoint
 type RecentMessage struct {
 	ID            string `json:"id"`
@@ -384,6 +458,11 @@ type RecentMessage struct {
 	IsFromMe      bool   `json:"is_from_me"`
 	MediaType     string `json:"media_type,omitempty"`
 	Transcription string `json:"transcription,omitempty"`
+	ReplyToID     string `json:"reply_to_id,omitempty"`
+	ReplyToSender string `json:"reply_to_sender,omitempty"`
+	QuotedContent string `json:"quoted_content,omitempty"`
+	Deleted       bool   `json:"deleted,omitempty"`
+	ReadAt        string `json:"read_at,omitempty"`
 }
 
 // GetRecentMessages returns messages from the last N hours with chat names
@@ -393,7 +472,9 @@ func (store *MessageStore) GetRecentMessages(hours int) ([]RecentMessage, error)
 	rows, err := store.db.Query(`
 		SELECT m.id, m.chat_jid, COALESCE(c.name, m.chat_jid) as chat_name,
 		       m.sender, COALESCE(m.content, '') as content, m.timestamp, m.is_from_me,
-		       COALESCE(m.media_type, '') as media_type, COALESCE(m.transcription, '') as transcription
+		       COALESCE(m.media_type, '') as media_type, COALESCE(m.transcription, '') as transcription,
+		       COALESCE(m.reply_to_id, '') as reply_to_id, COALESCE(m.reply_to_sender, '') as reply_to_sender,
+		       COALESCE(m.quoted_content, '') as quoted_content, m.deleted_at, m.read_at
 		FROM messages m
 		LEFT JOIN chats c ON m.chat_jid = c.jid
 		WHERE m.timestamp >= ?
@@ -408,69 +489,54 @@ func (store *MessageStore) GetRecentMessages(hours int) ([]RecentMessage, error)
 	for rows.Next() {
 		var msg RecentMessage
 		var ts time.Time
+		var deletedAt, readAt sql.NullTime
 		err := rows.Scan(&msg.ID, &msg.ChatJID, &msg.ChatName, &msg.Sender, &msg.Content,
-			&ts, &msg.IsFromMe, &msg.MediaType, &msg.Transcription)
+			&ts, &msg.IsFromMe, &msg.MediaType, &msg.Transcription,
+			&msg.ReplyToID, &msg.ReplyToSender, &msg.QuotedContent, &deletedAt, &readAt)
 		if err != nil {
 			return nil, err
 		}
 		msg.Timestamp = ts.Format(time.RFC3339)
+		msg.Deleted = deletedAt.Valid
+		if readAt.Valid {
+			msg.ReadAt = readAt.Time.Format(time.RFC3339)
+		}
 		messages = append(messages, msg)
 	}
 
 	return messages, nil
 }
 
-// transcribeAudio converts audio to WAV then transcribes using whisper-cli
-func transcribeAudio(audioPath string) (string, error) {
-	// Check whisper binary exists
-	if _, err := os.Stat(whisperBinPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("whisper-cli not found at %s, install with: brew install whisper-cpp", whisperBinPath)
+// extractContextInfo pulls the quoted-message linkage (stanza ID, quoting sender,
+// and a text rendering of the quoted content) off whichever message type carries
+// it, since ContextInfo lives on ExtendedTextMessage as well as every media message.
+func extractContextInfo(msg *waProto.Message) (stanzaID, participant, quotedContent string) {
+	if msg == nil {
+		return "", "", ""
 	}
 
-	// Check model exists
-	if _, err := os.Stat(whisperModelPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("whisper model not found at %s", whisperModelPath)
+	var ctx *waProto.ContextInfo
+	switch {
+	case msg.GetExtendedTextMessage() != nil:
+		ctx = msg.GetExtendedTextMessage().GetContextInfo()
+	case msg.GetImageMessage() != nil:
+		ctx = msg.GetImageMessage().GetContextInfo()
+	case msg.GetVideoMessage() != nil:
+		ctx = msg.GetVideoMessage().GetContextInfo()
+	case msg.GetAudioMessage() != nil:
+		ctx = msg.GetAudioMessage().GetContextInfo()
+	case msg.GetDocumentMessage() != nil:
+		ctx = msg.GetDocumentMessage().GetContextInfo()
 	}
 
-	// Convert to 16kHz mono WAV (whisper-cli requires wav input)
-	wavPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + "_transcribe.wav"
-	defer os.Remove(wavPath)
-
-	convertCmd := exec.Command(ffmpegBinPath,
-		"-i", audioPath,
-		"-ar", "16000",
-		"-ac", "1",
-		"-c:a", "pcm_s16le",
-		"-y",
-		wavPath,
-	)
-	convertOut, err := convertCmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("ffmpeg conversion failed: %v, output: %s", err, string(convertOut))
-	}
-
-	// Run whisper-cli
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	whisperCmd := exec.CommandContext(ctx, whisperBinPath,
-		"-m", whisperModelPath,
-		"-f", wavPath,
-		"--no-timestamps",
-	)
-
-	// Capture stdout separately from stderr
-	var stdout, stderr bytes.Buffer
-	whisperCmd.Stdout = &stdout
-	whisperCmd.Stderr = &stderr
-
-	err = whisperCmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("whisper-cli failed: %v, stderr: %s", err, stderr.String())
+	if ctx == nil {
+		return "", "", ""
 	}
 
-	transcription := strings.TrimSpace(stdout.String())
-	return transcription, nil
+	stanzaID = ctx.GetStanzaID()
+	participant = ctx.GetParticipant()
+	quotedContent = extractTextContent(ctx.GetQuotedMessage())
+	return stanzaID, participant, quotedContent
 }
 
 // Extract text content from a message
@@ -497,14 +563,42 @@ type SendMessageResponse struct {
 
 // SendMessageRequest represents the request body for the send message API
 type SendMessageRequest struct {
-	Recipient string `json:"recipient"`
-	Message   string `json:"message"`
-	MediaPath string `json:"media_path,omitempty"`
+	Recipient       string `json:"recipient"`
+	Message         string `json:"message"`
+	MediaPath       string `json:"media_path,omitempty"`
+	ReplyTo         string `json:"reply_to,omitempty"`
+	QuotedMessageID string `json:"quoted_message_id,omitempty"`
+}
+
+// buildContextInfo looks up the quoted message in the store and builds the ContextInfo
+// to attach to an outgoing message so it renders as a true threaded reply. Replying to a
+// media message requires the quoted message's sender appended to the stanza ID, delimited
+// with "/", or WhatsApp clients fail to resolve the quote.
+func buildContextInfo(messageStore *MessageStore, chatJID, replyTo string) *waProto.ContextInfo {
+	if replyTo == "" {
+		return nil
+	}
+
+	quotedSender, quotedContent, quotedMediaType, err := messageStore.GetMessageForQuote(replyTo, chatJID)
+	if err != nil {
+		return nil
+	}
+
+	stanzaID := replyTo
+	if quotedMediaType != "" {
+		stanzaID = replyTo + "/" + quotedSender
+	}
+
+	return &waProto.ContextInfo{
+		StanzaID:      proto.String(stanzaID),
+		Participant:   proto.String(quotedSender),
+		QuotedMessage: &waProto.Message{Conversation: proto.String(quotedContent)},
+	}
 }
 
 // Function to send a WhatsApp message
-func sendWhatsAppMessage(client *whatsmeow.Client, recipient string, message string, mediaPath string) (bool, string) {
-	if !client.IsConnected() {
+func sendWhatsAppMessage(client *whatsmeow.Client, messageStore *MessageStore, recipient string, message string, mediaPath string, replyTo string) (bool, string) {
+	if !reconnectSupervisor.CanSend() {
 		return false, "Not connected to WhatsApp"
 	}
 
@@ -530,6 +624,7 @@ func sendWhatsAppMessage(client *whatsmeow.Client, recipient string, message str
 	}
 
 	msg := &waProto.Message{}
+	ctxInfo := buildContextInfo(messageStore, recipientJID.String(), replyTo)
 
 	// Check if we have media to send
 	if mediaPath != "" {
@@ -593,6 +688,7 @@ func sendWhatsAppMessage(client *whatsmeow.Client, recipient string, message str
 				FileEncSHA256: resp.FileEncSHA256,
 				FileSHA256:    resp.FileSHA256,
 				FileLength:    &resp.FileLength,
+				ContextInfo:   ctxInfo,
 			}
 		case whatsmeow.MediaAudio:
 			var seconds uint32 = 30
@@ -619,6 +715,7 @@ func sendWhatsAppMessage(client *whatsmeow.Client, recipient string, message str
 				Seconds:       proto.Uint32(seconds),
 				PTT:           proto.Bool(true),
 				Waveform:      waveform,
+				ContextInfo:   ctxInfo,
 			}
 		case whatsmeow.MediaVideo:
 			msg.VideoMessage = &waProto.VideoMessage{
@@ -630,6 +727,7 @@ func sendWhatsAppMessage(client *whatsmeow.Client, recipient string, message str
 				FileEncSHA256: resp.FileEncSHA256,
 				FileSHA256:    resp.FileSHA256,
 				FileLength:    &resp.FileLength,
+				ContextInfo:   ctxInfo,
 			}
 		case whatsmeow.MediaDocument:
 			msg.DocumentMessage = &waProto.DocumentMessage{
@@ -642,8 +740,14 @@ func sendWhatsAppMessage(client *whatsmeow.Client, recipient string, message str
 				FileEncSHA256: resp.FileEncSHA256,
 				FileSHA256:    resp.FileSHA256,
 				FileLength:    &resp.FileLength,
+				ContextInfo:   ctxInfo,
 			}
 		}
+	} else if ctxInfo != nil {
+		msg.ExtendedTextMessage = &waProto.ExtendedTextMessage{
+			Text:        proto.String(message),
+			ContextInfo: ctxInfo,
+		}
 	} else {
 		msg.Conversation = proto.String(message)
 	}
@@ -692,10 +796,17 @@ func extractMediaInfo(msg *waProto.Message) (mediaType string, filename string,
 }
 
 // Handle regular incoming messages with media support
-func handleMessage(client *whatsmeow.Client, messageStore *MessageStore, msg *events.Message, logger waLog.Logger) {
+func handleMessage(client *whatsmeow.Client, messageStore *MessageStore, transcriptionPool *TranscriptionWorkerPool, msg *events.Message, logger waLog.Logger) {
 	chatJID := msg.Info.Chat.String()
 	sender := msg.Info.Sender.User
 
+	if !shouldStoreMessage(msg.Info.Sender.String(), chatJID) {
+		return
+	}
+	if muted, err := messageStore.IsChatMuted(chatJID); err == nil && muted {
+		return
+	}
+
 	name := GetChatName(client, messageStore, msg.Info.Chat, chatJID, nil, sender, logger)
 
 	err := messageStore.StoreChat(chatJID, name, msg.Info.Timestamp)
@@ -703,6 +814,22 @@ func handleMessage(client *whatsmeow.Client, messageStore *MessageStore, msg *ev
 		logger.Warnf("Failed to store chat: %v", err)
 	}
 
+	if protocolMsg := msg.Message.GetProtocolMessage(); protocolMsg != nil {
+		switch protocolMsg.GetType() {
+		case waProto.ProtocolMessage_REVOKE:
+			handleRevoke(messageStore, chatJID, protocolMsg, logger)
+			return
+		case waProto.ProtocolMessage_MESSAGE_EDIT:
+			handleEdit(messageStore, chatJID, protocolMsg, logger)
+			return
+		}
+	}
+
+	if reaction := msg.Message.GetReactionMessage(); reaction != nil {
+		handleReaction(messageStore, chatJID, msg.Info.Sender.String(), reaction, logger)
+		return
+	}
+
 	content := extractTextContent(msg.Message)
 	mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength := extractMediaInfo(msg.Message)
 
@@ -718,6 +845,24 @@ func handleMessage(client *whatsmeow.Client, messageStore *MessageStore, msg *ev
 	if err != nil {
 		logger.Warnf("Failed to store message: %v", err)
 	} else {
+		if stanzaID, participant, quotedContent := extractContextInfo(msg.Message); stanzaID != "" {
+			if rErr := messageStore.StoreReplyInfo(msg.Info.ID, chatJID, stanzaID, participant, quotedContent); rErr != nil {
+				logger.Warnf("Failed to store reply info: %v", rErr)
+			}
+		}
+
+		if msg.Info.Chat.Server == "g.us" {
+			participantCount := 0
+			subject := ""
+			if group, gErr := messageStore.GetGroup(chatJID); gErr == nil {
+				participantCount = len(group.Participants)
+				subject = group.Subject
+			}
+			if mErr := messageStore.StoreGroupMessageMeta(msg.Info.ID, chatJID, msg.Info.PushName, subject, participantCount); mErr != nil {
+				logger.Warnf("Failed to store group message metadata: %v", mErr)
+			}
+		}
+
 		timestamp := msg.Info.Timestamp.Format("2006-01-02 15:04:05")
 		direction := "←"
 		if msg.Info.IsFromMe {
@@ -730,28 +875,43 @@ func handleMessage(client *whatsmeow.Client, messageStore *MessageStore, msg *ev
 			fmt.Printf("[%s] %s %s: %s\n", timestamp, direction, sender, content)
 		}
 
-		// Auto-transcribe audio messages in background
-		if mediaType == "audio" {
-			go func(msgID, cJID string) {
-				logger.Infof("Auto-transcribing voice note %s in %s...", msgID, cJID)
-				success, _, _, audioPath, dlErr := downloadMedia(client, messageStore, msgID, cJID)
-				if !success || dlErr != nil {
-					logger.Warnf("Failed to download audio for transcription: %v", dlErr)
-					return
-				}
-				transcription, tErr := transcribeAudio(audioPath)
-				if tErr != nil {
-					logger.Warnf("Failed to transcribe audio: %v", tErr)
-					return
-				}
-				if transcription != "" {
-					if sErr := messageStore.StoreTranscription(msgID, cJID, transcription); sErr != nil {
-						logger.Warnf("Failed to store transcription: %v", sErr)
-					} else {
-						logger.Infof("Transcribed voice note %s: %s", msgID, transcription)
-					}
+		eventBus.Publish(EventKindMessage, RecentMessage{
+			ID:        msg.Info.ID,
+			ChatJID:   chatJID,
+			ChatName:  name,
+			Sender:    sender,
+			Content:   content,
+			Timestamp: msg.Info.Timestamp.Format(time.RFC3339),
+			IsFromMe:  msg.Info.IsFromMe,
+			MediaType: mediaType,
+		})
+
+		// Queue audio messages for transcription on the bounded worker pool instead of
+		// spawning an unbounded goroutine per voice note.
+		if mediaType == "audio" && shouldTranscribeChat(chatJID) {
+			transcriptionPool.Enqueue(msg.Info.ID, chatJID, msg.Info.IsFromMe)
+		}
+
+		if !msg.Info.IsFromMe && content != "" {
+			dispatchResponders(client, messageStore, chatJID, sender, content, logger)
+		}
+
+		if bridge != nil {
+			relayMsg := RelayMessage{
+				ChatJID:    chatJID,
+				Sender:     sender,
+				SenderName: msg.Info.PushName,
+				Content:    content,
+				MediaType:  mediaType,
+			}
+			if stanzaID, _, _ := extractContextInfo(msg.Message); stanzaID != "" {
+				relayMsg.ReplyToID = stanzaID
+			}
+			go func() {
+				if err := bridge.Send(context.Background(), relayMsg); err != nil {
+					logger.Warnf("Failed to relay message %s to bridge: %v", msg.Info.ID, err)
 				}
-			}(msg.Info.ID, chatJID)
+			}()
 		}
 	}
 }
@@ -960,7 +1120,7 @@ func resolveContactName(client *whatsmeow.Client, jidStr string) string {
 }
 
 // Start a REST API server to expose the WhatsApp client functionality
-func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, messageStore *MessageStore, port int, logger waLog.Logger) {
+func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, messageStore *MessageStore, transcriptionPool *TranscriptionWorkerPool, port int, logger waLog.Logger) {
 	// Serve web UI at root
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
@@ -1022,7 +1182,24 @@ func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, me
 		if client.IsConnected() {
 			status = "connected"
 		}
-		json.NewEncoder(w).Encode(map[string]string{"status": status})
+
+		lastConnected, attempts, errorHistory := reconnectSupervisor.snapshot()
+
+		health := map[string]interface{}{
+			"status":             status,
+			"connection_state":   reconnectSupervisor.currentState(),
+			"reconnect_attempts": attempts,
+			"error_history":      errorHistory,
+		}
+		if !lastConnected.IsZero() {
+			health["last_connected"] = lastConnected.Format(time.RFC3339)
+		}
+		if client.Store.ID != nil {
+			health["jid"] = client.Store.ID.String()
+			health["push_name"] = client.Store.PushName
+		}
+
+		json.NewEncoder(w).Encode(health)
 	})
 
 	// Recent messages endpoint (auth required)
@@ -1112,7 +1289,12 @@ func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, me
 
 		fmt.Println("Received request to send message", req.Message, req.MediaPath)
 
-		success, message := sendWhatsAppMessage(client, req.Recipient, req.Message, req.MediaPath)
+		replyTo := req.ReplyTo
+		if replyTo == "" {
+			replyTo = req.QuotedMessageID
+		}
+
+		success, message := sendWhatsAppMessage(client, messageStore, req.Recipient, req.Message, req.MediaPath, replyTo)
 		fmt.Println("Message sent", success, message)
 
 		w.Header().Set("Content-Type", "application/json")
@@ -1235,7 +1417,10 @@ func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, me
 			return
 		}
 
-		transcription, err := transcribeAudio(audioPath)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		transcription, language, err := transcriptionPool.transcriber.Transcribe(ctx, audioPath)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1245,7 +1430,7 @@ func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, me
 			return
 		}
 
-		if storeErr := messageStore.StoreTranscription(req.MessageID, req.ChatJID, transcription); storeErr != nil {
+		if storeErr := messageStore.StoreTranscriptionWithLanguage(req.MessageID, req.ChatJID, transcription, language); storeErr != nil {
 			fmt.Printf("Warning: failed to store transcription: %v\n", storeErr)
 		}
 
@@ -1256,6 +1441,33 @@ func startRESTServer(client *whatsmeow.Client, container *sqlstore.Container, me
 		})
 	})
 
+	hsManager, err := NewHistorySyncManager(client, messageStore, logger)
+	if err != nil {
+		logger.Errorf("Failed to initialize history sync manager: %v", err)
+		return
+	}
+	historySyncManager = hsManager
+
+	sessionManager = NewSessionManager(container, filepath.Join(storeDir, "devices"), logger)
+	if err := sessionManager.LoadExistingSessions(context.Background()); err != nil {
+		logger.Warnf("Failed to load existing multi-device sessions: %v", err)
+	}
+
+	registerGroupHandlers(client, messageStore, logger)
+	registerContactHandlers(client, logger)
+	registerReactionHandlers(client, messageStore, logger)
+	registerResponderHandlers()
+	registerHistorySyncHandlers(historySyncManager)
+	registerVoiceNoteHandler(client, messageStore, logger)
+	registerEventStreamHandler(eventBus)
+	registerWebSocketHandler(newWSHub(eventBus, logger), logger)
+	registerFilterHandlers(messageStore)
+	registerPresenceHandlers(messageStore)
+	registerSessionHandlers(sessionManager, logger)
+	if platformBridge, ok := bridge.(*platformBridger); ok {
+		registerBridgeIncomingHandler(platformBridge, logger)
+	}
+
 	// Start the server
 	serverAddr := fmt.Sprintf(":%d", port)
 	fmt.Printf("Starting REST API server on %s...\n", serverAddr)
@@ -1340,18 +1552,72 @@ func main() {
 	}
 	defer messageStore.Close()
 
+	if err := createPresenceTables(messageStore); err != nil {
+		logger.Errorf("Failed to create presence tables: %v", err)
+		return
+	}
+
+	eventBus = NewEventBus(logger)
+
+	loadFilterConfig(logger)
+	watchFilterConfigReload(logger)
+	watchFilterConfigFile(logger)
+
+	bridge, err = NewBridgerFromEnv(logger)
+	if err != nil {
+		logger.Errorf("Failed to initialize bridge: %v", err)
+		return
+	}
+	if bridge != nil {
+		go runBridgeRelay(context.Background(), client, messageStore, bridge, logger)
+	}
+
+	transcriptionPool, err := StartTranscriptionWorkerPool(client, messageStore, NewTranscriberFromEnv(), defaultTranscriptionWorkers, logger)
+	if err != nil {
+		logger.Errorf("Failed to start transcription worker pool: %v", err)
+		return
+	}
+
 	client.AddEventHandler(func(evt interface{}) {
 		switch v := evt.(type) {
 		case *events.Message:
-			handleMessage(client, messageStore, v, logger)
+			handleMessage(client, messageStore, transcriptionPool, v, logger)
 		case *events.HistorySync:
-			handleHistorySync(client, messageStore, v, logger)
+			historySyncManager.Ingest(client, v, logger)
 		case *events.Connected:
 			logger.Infof("Connected to WhatsApp")
 			setAuthState("connected", "", "")
+			reconnectSupervisor.recordConnected()
 		case *events.LoggedOut:
-			logger.Warnf("Device logged out")
+			logger.Warnf("Device logged out, a new QR pairing is required")
 			setAuthState("logged_out", "", "")
+			reconnectSupervisor.recordLoggedOut()
+		case *events.Disconnected:
+			logger.Warnf("Disconnected from WhatsApp, starting reconnect supervisor")
+			reconnectSupervisor.recordError(fmt.Errorf("disconnected"))
+			reconnectSupervisor.scheduleReconnect(client, logger)
+		case *events.StreamError:
+			logger.Warnf("Stream error, starting reconnect supervisor: %s", v.Code)
+			reconnectSupervisor.recordError(fmt.Errorf("stream error: %s", v.Code))
+			reconnectSupervisor.scheduleReconnect(client, logger)
+		case *events.StreamReplaced:
+			logger.Warnf("Stream replaced by another session, starting reconnect supervisor")
+			reconnectSupervisor.recordError(fmt.Errorf("stream replaced"))
+			reconnectSupervisor.scheduleReconnect(client, logger)
+		case *events.TemporaryBan:
+			logger.Warnf("Temporarily banned until %s: %s", v.Expire, v.Code)
+			reconnectSupervisor.recordError(fmt.Errorf("temporary ban (%s) until %s", v.Code, v.Expire))
+			reconnectSupervisor.scheduleReconnect(client, logger)
+		case *events.GroupInfo:
+			handleGroupInfoChange(client, messageStore, v, logger)
+		case *events.JoinedGroup:
+			handleJoinedGroup(client, messageStore, v, logger)
+		case *events.Receipt:
+			handleReceipt(messageStore, v, logger)
+		case *events.Presence:
+			handlePresence(messageStore, v, logger)
+		case *events.ChatPresence:
+			handleChatPresence(messageStore, v, logger)
 		}
 	})
 
@@ -1364,7 +1630,7 @@ func main() {
 	}
 
 	// Start HTTP server FIRST so web UI is available during auth
-	startRESTServer(client, container, messageStore, port, logger)
+	startRESTServer(client, container, messageStore, transcriptionPool, port, logger)
 	fmt.Printf("REST server is running on port %d\n", port)
 
 	// Now handle authentication
@@ -1413,6 +1679,7 @@ func main() {
 		} else {
 			setAuthState("connected", "", "")
 			fmt.Println("Connected to WhatsApp!")
+			go syncAllGroups(client, messageStore, logger)
 		}
 	}
 
@@ -1591,7 +1858,7 @@ func handleHistorySync(client *whatsmeow.Client, messageStore *MessageStore, his
 
 // Request history sync from the server
 func requestHistorySync(client *whatsmeow.Client) {
-	if client == nil || !client.IsConnected() || client.Store.ID == nil {
+	if client == nil || !reconnectSupervisor.CanSend() || client.Store.ID == nil {
 		fmt.Println("Client is not ready. Cannot request history sync.")
 		return
 	}
@@ -1614,79 +1881,6 @@ func requestHistorySync(client *whatsmeow.Client) {
 	}
 }
 
-// analyzeOggOpus tries to extract duration and generate a simple waveform from an Ogg Opus file
-func analyzeOggOpus(data []byte) (duration uint32, waveform []byte, err error) {
-	if len(data) < 4 || string(data[0:4]) != "OggS" {
-		return 0, nil, fmt.Errorf("not a valid Ogg file (missing OggS signature)")
-	}
-
-	var lastGranule uint64
-	var sampleRate uint32 = 48000
-	var preSkip uint16 = 0
-	var foundOpusHead bool
-
-	for i := 0; i < len(data); {
-		if i+27 >= len(data) {
-			break
-		}
-
-		if string(data[i:i+4]) != "OggS" {
-			i++
-			continue
-		}
-
-		granulePos := binary.LittleEndian.Uint64(data[i+6 : i+14])
-		pageSeqNum := binary.LittleEndian.Uint32(data[i+18 : i+22])
-		numSegments := int(data[i+26])
-
-		if i+27+numSegments >= len(data) {
-			break
-		}
-		segmentTable := data[i+27 : i+27+numSegments]
-
-		pageSize := 27 + numSegments
-		for _, segLen := range segmentTable {
-			pageSize += int(segLen)
-		}
-
-		if !foundOpusHead && pageSeqNum <= 1 {
-			pageData := data[i : i+pageSize]
-			headPos := bytes.Index(pageData, []byte("OpusHead"))
-			if headPos >= 0 && headPos+12 < len(pageData) {
-				headPos += 8
-				if headPos+12 <= len(pageData) {
-					preSkip = binary.LittleEndian.Uint16(pageData[headPos+10 : headPos+12])
-					sampleRate = binary.LittleEndian.Uint32(pageData[headPos+12 : headPos+16])
-					foundOpusHead = true
-				}
-			}
-		}
-
-		if granulePos != 0 {
-			lastGranule = granulePos
-		}
-
-		i += pageSize
-	}
-
-	if lastGranule > 0 {
-		durationSeconds := float64(lastGranule-uint64(preSkip)) / float64(sampleRate)
-		duration = uint32(math.Ceil(durationSeconds))
-	} else {
-		durationEstimate := float64(len(data)) / 2000.0
-		duration = uint32(durationEstimate)
-	}
-
-	if duration < 1 {
-		duration = 1
-	} else if duration > 300 {
-		duration = 300
-	}
-
-	waveform = placeholderWaveform(duration)
-	return duration, waveform, nil
-}
-
 func min(x, y int) int {
 	if x < y {
 		return x
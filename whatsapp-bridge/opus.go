@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/hraban/opus"
+)
+
+// opusFrameSizeMsByConfig maps an Opus TOC config number (0-31, RFC 6716 Section 3.1,
+// Table 2) to the duration of a single frame in milliseconds.
+var opusFrameSizeMsByConfig = [32]float64{
+	10, 20, 40, 60, // SILK-only NB
+	10, 20, 40, 60, // SILK-only MB
+	10, 20, 40, 60, // SILK-only WB
+	10, 20, 10, 20, // Hybrid SWB/FB
+	2.5, 5, 10, 20, // CELT-only NB
+	2.5, 5, 10, 20, // CELT-only WB
+	2.5, 5, 10, 20, // CELT-only SWB
+	2.5, 5, 10, 20, // CELT-only FB
+}
+
+// opusPacketFrames returns the number of frames packed into a single Opus packet
+// (RFC 6716 Section 3.1) and the duration of each frame in milliseconds (2.5 to 60).
+func opusPacketFrames(packet []byte) (frameCount int, frameSizeMs float64, err error) {
+	if len(packet) == 0 {
+		return 0, 0, fmt.Errorf("empty opus packet")
+	}
+
+	toc := packet[0]
+	config := int(toc >> 3)
+	frameSizeMs = opusFrameSizeMsByConfig[config]
+
+	switch toc & 0x3 {
+	case 0:
+		frameCount = 1
+	case 1, 2:
+		frameCount = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, 0, fmt.Errorf("truncated code-3 opus packet")
+		}
+		frameCount = int(packet[1] & 0x3F)
+		if frameCount == 0 {
+			return 0, 0, fmt.Errorf("code-3 opus packet with zero frames")
+		}
+	default:
+		return 0, 0, fmt.Errorf("unreachable opus TOC code")
+	}
+
+	return frameCount, frameSizeMs, nil
+}
+
+// oggOpusStream holds the audio packets and header fields recovered from an Ogg
+// container carrying an Opus bitstream.
+type oggOpusStream struct {
+	packets    [][]byte
+	preSkip    uint16
+	sampleRate uint32
+	channels   int
+}
+
+// parseOggOpusPackets walks every Ogg page, reassembles the lacing-table segments into
+// whole packets (joining continued packets across page boundaries per the "last segment
+// length == 255" rule), and splits off the two Opus header packets (OpusHead, OpusTags)
+// so the remainder is pure audio.
+func parseOggOpusPackets(data []byte) (*oggOpusStream, error) {
+	if len(data) < 4 || string(data[0:4]) != "OggS" {
+		return nil, fmt.Errorf("not a valid Ogg file (missing OggS signature)")
+	}
+
+	stream := &oggOpusStream{sampleRate: 48000, channels: 1}
+
+	var packets [][]byte
+	var current []byte
+	var sawOpusHead bool
+
+	for i := 0; i < len(data); {
+		if i+27 > len(data) || string(data[i:i+4]) != "OggS" {
+			break
+		}
+
+		numSegments := int(data[i+26])
+		if i+27+numSegments > len(data) {
+			break
+		}
+		segmentTable := data[i+27 : i+27+numSegments]
+
+		pos := i + 27 + numSegments
+		for _, segLen := range segmentTable {
+			end := pos + int(segLen)
+			if end > len(data) {
+				return nil, fmt.Errorf("truncated Ogg page")
+			}
+			current = append(current, data[pos:end]...)
+			pos = end
+
+			// A segment shorter than 255 bytes ends the packet; a 255-byte segment means the
+			// packet continues in the next segment, which may be on the next page.
+			if segLen < 255 {
+				packets = append(packets, current)
+				current = nil
+			}
+		}
+
+		i = pos
+	}
+	if len(current) > 0 {
+		packets = append(packets, current)
+	}
+
+	if len(packets) < 2 {
+		return nil, fmt.Errorf("ogg opus stream has no audio packets")
+	}
+
+	head := packets[0]
+	headPos := bytes.Index(head, []byte("OpusHead"))
+	if headPos < 0 || headPos+19 > len(head) {
+		return nil, fmt.Errorf("missing OpusHead packet")
+	}
+	stream.channels = int(head[headPos+9])
+	stream.preSkip = binary.LittleEndian.Uint16(head[headPos+10 : headPos+12])
+	stream.sampleRate = binary.LittleEndian.Uint32(head[headPos+12 : headPos+16])
+	if stream.channels < 1 {
+		stream.channels = 1
+	}
+	sawOpusHead = true
+
+	if !sawOpusHead {
+		return nil, fmt.Errorf("missing OpusHead packet")
+	}
+
+	stream.packets = packets[2:] // skip OpusHead and OpusTags
+	return stream, nil
+}
+
+// decodeOpusStream decodes every audio packet to 16-bit PCM via a real Opus decoder,
+// and returns the concatenated mono samples (downmixed if the stream is stereo) plus
+// the total duration in seconds (sum of per-packet frame durations, minus pre-skip).
+func decodeOpusStream(stream *oggOpusStream) (samples []int16, duration float64, err error) {
+	decoder, err := opus.NewDecoder(int(stream.sampleRate), stream.channels)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create opus decoder: %v", err)
+	}
+
+	pcmBuf := make([]int16, 5760*stream.channels) // max frame size at 48kHz (120ms)
+	var totalSamples int64
+
+	for _, packet := range stream.packets {
+		frameCount, frameSizeMs, fErr := opusPacketFrames(packet)
+		if fErr != nil {
+			return nil, 0, fErr
+		}
+
+		n, dErr := decoder.Decode(packet, pcmBuf)
+		if dErr != nil {
+			return nil, 0, fmt.Errorf("opus decode failed: %v", dErr)
+		}
+
+		if stream.channels == 1 {
+			samples = append(samples, pcmBuf[:n]...)
+		} else {
+			for s := 0; s < n; s++ {
+				var sum int32
+				for c := 0; c < stream.channels; c++ {
+					sum += int32(pcmBuf[s*stream.channels+c])
+				}
+				samples = append(samples, int16(sum/int32(stream.channels)))
+			}
+		}
+
+		totalSamples += int64(frameCount) * int64(frameSizeMs*float64(stream.sampleRate)/1000)
+	}
+
+	durationSamples := totalSamples - int64(stream.preSkip)
+	if durationSamples < 0 {
+		durationSamples = 0
+	}
+	duration = float64(durationSamples) / float64(stream.sampleRate)
+	return samples, duration, nil
+}
+
+// waveformFromPCM computes the WhatsApp waveform (64 bytes, 0-100 normalized RMS per
+// window) from decoded mono PCM samples.
+func waveformFromPCM(samples []int16, sampleRate uint32) []byte {
+	const waveformLength = 64
+	waveform := make([]byte, waveformLength)
+	if len(samples) == 0 {
+		return waveform
+	}
+
+	windowSize := len(samples) / waveformLength
+	if windowSize < 1 {
+		windowSize = 1
+	}
+
+	var peak float64
+	rms := make([]float64, waveformLength)
+	for i := 0; i < waveformLength; i++ {
+		start := i * windowSize
+		if start >= len(samples) {
+			break
+		}
+		end := start + windowSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		var sumSquares float64
+		for _, s := range samples[start:end] {
+			v := float64(s)
+			sumSquares += v * v
+		}
+		r := math.Sqrt(sumSquares / float64(end-start))
+		rms[i] = r
+		if r > peak {
+			peak = r
+		}
+	}
+
+	if peak == 0 {
+		return waveform
+	}
+	for i, r := range rms {
+		waveform[i] = byte(math.Min(100, (r/peak)*100))
+	}
+	return waveform
+}
+
+// analyzeOggOpus extracts duration and a real amplitude waveform from an Ogg Opus file by
+// decoding it through an actual Opus decoder. If decoding fails for any reason (corrupt
+// stream, unsupported packet layout), it falls back to the page-header duration heuristic
+// and a synthetic placeholder waveform, logging that a placeholder was used.
+func analyzeOggOpus(data []byte) (duration uint32, waveform []byte, err error) {
+	stream, err := parseOggOpusPackets(data)
+	if err != nil {
+		return analyzeOggOpusFallback(data, err)
+	}
+
+	samples, durationSeconds, err := decodeOpusStream(stream)
+	if err != nil {
+		return analyzeOggOpusFallback(data, err)
+	}
+
+	duration = uint32(math.Ceil(durationSeconds))
+	if duration < 1 {
+		duration = 1
+	} else if duration > 300 {
+		duration = 300
+	}
+
+	return duration, waveformFromPCM(samples, stream.sampleRate), nil
+}
+
+// analyzeOggOpusFallback re-derives a rough duration from the Ogg page granule positions
+// alone (unreliable when the final page is missing, but better than nothing) and returns
+// a synthetic waveform in place of real decoded amplitude.
+func analyzeOggOpusFallback(data []byte, decodeErr error) (duration uint32, waveform []byte, err error) {
+	fmt.Printf("Opus decode failed, falling back to placeholder waveform: %v\n", decodeErr)
+
+	if len(data) < 4 || string(data[0:4]) != "OggS" {
+		return 0, nil, fmt.Errorf("not a valid Ogg file (missing OggS signature)")
+	}
+
+	var lastGranule uint64
+	var sampleRate uint32 = 48000
+	var preSkip uint16 = 0
+	var foundOpusHead bool
+
+	for i := 0; i < len(data); {
+		if i+27 >= len(data) {
+			break
+		}
+		if string(data[i:i+4]) != "OggS" {
+			i++
+			continue
+		}
+
+		granulePos := binary.LittleEndian.Uint64(data[i+6 : i+14])
+		pageSeqNum := binary.LittleEndian.Uint32(data[i+18 : i+22])
+		numSegments := int(data[i+26])
+
+		if i+27+numSegments >= len(data) {
+			break
+		}
+		segmentTable := data[i+27 : i+27+numSegments]
+
+		pageSize := 27 + numSegments
+		for _, segLen := range segmentTable {
+			pageSize += int(segLen)
+		}
+
+		if !foundOpusHead && pageSeqNum <= 1 {
+			pageData := data[i : i+pageSize]
+			headPos := bytes.Index(pageData, []byte("OpusHead"))
+			if headPos >= 0 && headPos+12 < len(pageData) {
+				headPos += 8
+				if headPos+12 <= len(pageData) {
+					preSkip = binary.LittleEndian.Uint16(pageData[headPos+10 : headPos+12])
+					sampleRate = binary.LittleEndian.Uint32(pageData[headPos+12 : headPos+16])
+					foundOpusHead = true
+				}
+			}
+		}
+
+		if granulePos != 0 {
+			lastGranule = granulePos
+		}
+
+		i += pageSize
+	}
+
+	if lastGranule > 0 {
+		durationSeconds := float64(lastGranule-uint64(preSkip)) / float64(sampleRate)
+		duration = uint32(math.Ceil(durationSeconds))
+	} else {
+		duration = uint32(float64(len(data)) / 2000.0)
+	}
+
+	if duration < 1 {
+		duration = 1
+	} else if duration > 300 {
+		duration = 300
+	}
+
+	return duration, placeholderWaveform(duration), nil
+}
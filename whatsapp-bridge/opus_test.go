@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+func TestOpusPacketFrames(t *testing.T) {
+	tests := []struct {
+		name       string
+		packet     []byte
+		wantCount  int
+		wantSizeMs float64
+		wantErr    bool
+	}{
+		{
+			name:       "code 0, single CELT-only NB frame (config 16, 2.5ms)",
+			packet:     []byte{16 << 3},
+			wantCount:  1,
+			wantSizeMs: 2.5,
+		},
+		{
+			name:       "code 1, two equal-size frames (config 0, 10ms SILK NB)",
+			packet:     []byte{0<<3 | 1, 0x00},
+			wantCount:  2,
+			wantSizeMs: 10,
+		},
+		{
+			name:       "code 2, two different-size frames (config 3, 60ms SILK NB)",
+			packet:     []byte{3<<3 | 2, 0x01, 0xAA},
+			wantCount:  2,
+			wantSizeMs: 60,
+		},
+		{
+			name:       "code 3, arbitrary frame count (config 22, 10ms CELT WB)",
+			packet:     []byte{22<<3 | 3, 5},
+			wantCount:  5,
+			wantSizeMs: 10,
+		},
+		{
+			name:    "empty packet errors",
+			packet:  []byte{},
+			wantErr: true,
+		},
+		{
+			name:    "truncated code-3 packet errors",
+			packet:  []byte{20<<3 | 3},
+			wantErr: true,
+		},
+		{
+			name:    "code-3 packet with zero frames errors",
+			packet:  []byte{20<<3 | 3, 0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, sizeMs, err := opusPacketFrames(tt.packet)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("opusPacketFrames() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("opusPacketFrames() unexpected error: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("frameCount = %d, want %d", count, tt.wantCount)
+			}
+			if sizeMs != tt.wantSizeMs {
+				t.Errorf("frameSizeMs = %v, want %v", sizeMs, tt.wantSizeMs)
+			}
+		})
+	}
+}
+
+func TestWaveformFromPCM(t *testing.T) {
+	t.Run("no samples returns all-zero waveform", func(t *testing.T) {
+		got := waveformFromPCM(nil, 48000)
+		if len(got) != 64 {
+			t.Fatalf("len(waveform) = %d, want 64", len(got))
+		}
+		for i, b := range got {
+			if b != 0 {
+				t.Errorf("waveform[%d] = %d, want 0", i, b)
+			}
+		}
+	})
+
+	t.Run("silence stays all-zero", func(t *testing.T) {
+		samples := make([]int16, 48000)
+		got := waveformFromPCM(samples, 48000)
+		for i, b := range got {
+			if b != 0 {
+				t.Errorf("waveform[%d] = %d, want 0", i, b)
+			}
+		}
+	})
+
+	t.Run("loudest window normalizes to 100", func(t *testing.T) {
+		samples := make([]int16, 6400)
+		// Make the last window much louder than the rest.
+		for i := 6300; i < 6400; i++ {
+			samples[i] = 32767
+		}
+		got := waveformFromPCM(samples, 48000)
+		if got[63] != 100 {
+			t.Errorf("waveform[63] = %d, want 100 (loudest window)", got[63])
+		}
+		for i := 0; i < 62; i++ {
+			if got[i] != 0 {
+				t.Errorf("waveform[%d] = %d, want 0 (silent window)", i, got[i])
+			}
+		}
+	})
+}
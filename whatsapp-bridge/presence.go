@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// createPresenceTables adds the presence and chat_state tables used to track online
+// status and typing/recording indicators, giving the daily summary richer signal than
+// raw messages alone (e.g. "Alice was typing for 5 minutes before her voice note").
+func createPresenceTables(store *MessageStore) error {
+	_, err := store.db.Exec(`
+		CREATE TABLE IF NOT EXISTS presence (
+			jid TEXT PRIMARY KEY,
+			last_seen TIMESTAMP,
+			is_online BOOLEAN
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_state (
+			chat_jid TEXT,
+			sender TEXT,
+			state TEXT,
+			updated_at TIMESTAMP,
+			PRIMARY KEY (chat_jid, sender)
+		);
+	`)
+	return err
+}
+
+// PresenceInfo is the stored online/offline state for a single JID.
+type PresenceInfo struct {
+	JID      string    `json:"jid"`
+	IsOnline bool      `json:"is_online"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// StorePresence upserts a contact's online status.
+func (store *MessageStore) StorePresence(jid string, isOnline bool, lastSeen time.Time) error {
+	_, err := store.db.Exec(
+		"INSERT OR REPLACE INTO presence (jid, last_seen, is_online) VALUES (?, ?, ?)",
+		jid, lastSeen, isOnline,
+	)
+	return err
+}
+
+// GetPresence returns the last known presence for a JID.
+func (store *MessageStore) GetPresence(jid string) (*PresenceInfo, error) {
+	var info PresenceInfo
+	info.JID = jid
+	err := store.db.QueryRow(
+		"SELECT last_seen, is_online FROM presence WHERE jid = ?", jid,
+	).Scan(&info.LastSeen, &info.IsOnline)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// StoreChatState upserts a sender's typing/recording/paused state within a chat.
+func (store *MessageStore) StoreChatState(chatJID, sender, state string, updatedAt time.Time) error {
+	_, err := store.db.Exec(
+		"INSERT OR REPLACE INTO chat_state (chat_jid, sender, state, updated_at) VALUES (?, ?, ?, ?)",
+		chatJID, sender, state, updatedAt,
+	)
+	return err
+}
+
+// RecentActivity summarizes the most recent chat_state entry for a chat, e.g. for
+// showing "Alice is typing" alongside a chat listing.
+func (store *MessageStore) RecentActivity(chatJID string) (sender, state string, updatedAt time.Time, err error) {
+	err = store.db.QueryRow(
+		"SELECT sender, state, updated_at FROM chat_state WHERE chat_jid = ? ORDER BY updated_at DESC LIMIT 1",
+		chatJID,
+	).Scan(&sender, &state, &updatedAt)
+	return sender, state, updatedAt, err
+}
+
+// chatPresenceStateName maps whatsmeow's ChatPresence/media combination onto the
+// composing|recording|paused vocabulary used by the chat_state table.
+func chatPresenceStateName(evt *events.ChatPresence) string {
+	if evt.State == "composing" && evt.Media == "audio" {
+		return "recording"
+	}
+	return string(evt.State)
+}
+
+// handlePresence persists an events.Presence update (a contact going online/offline).
+func handlePresence(messageStore *MessageStore, evt *events.Presence, logger waLog.Logger) {
+	lastSeen := evt.LastSeen
+	if lastSeen.IsZero() {
+		lastSeen = time.Now()
+	}
+	if err := messageStore.StorePresence(evt.From.String(), !evt.Unavailable, lastSeen); err != nil {
+		logger.Warnf("Failed to store presence for %s: %v", evt.From, err)
+		return
+	}
+
+	eventBus.Publish(EventKindPresence, PresenceInfo{
+		JID:      evt.From.String(),
+		IsOnline: !evt.Unavailable,
+		LastSeen: lastSeen,
+	})
+}
+
+// handleChatPresence persists composing/recording/paused indicators within a chat.
+func handleChatPresence(messageStore *MessageStore, evt *events.ChatPresence, logger waLog.Logger) {
+	chatJID := evt.MessageSource.Chat.String()
+	sender := evt.MessageSource.Sender.String()
+	state := chatPresenceStateName(evt)
+
+	if err := messageStore.StoreChatState(chatJID, sender, state, time.Now()); err != nil {
+		logger.Warnf("Failed to store chat state for %s in %s: %v", sender, chatJID, err)
+		return
+	}
+
+	eventBus.Publish(EventKindPresence, map[string]interface{}{
+		"chat_jid": chatJID,
+		"sender":   sender,
+		"state":    state,
+	})
+}
+
+// registerPresenceHandlers exposes presence and chat-listing endpoints.
+func registerPresenceHandlers(messageStore *MessageStore) {
+	http.HandleFunc("/api/presence/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		jid := strings.TrimPrefix(r.URL.Path, "/api/presence/")
+		if jid == "" {
+			http.Error(w, "JID is required", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		info, err := messageStore.GetPresence(jid)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "no presence known for this JID"})
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(info)
+	})
+
+	http.HandleFunc("/api/chats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		chats, err := messageStore.GetChats()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		type chatListing struct {
+			JID             string `json:"jid"`
+			LastMessageTime string `json:"last_message_time"`
+			RecentActivity  string `json:"recent_activity,omitempty"`
+		}
+
+		listings := make([]chatListing, 0, len(chats))
+		for jid, lastMessageTime := range chats {
+			listing := chatListing{JID: jid, LastMessageTime: lastMessageTime.Format(time.RFC3339)}
+			if sender, state, updatedAt, err := messageStore.RecentActivity(jid); err == nil && time.Since(updatedAt) < 30*time.Second {
+				listing.RecentActivity = fmt.Sprintf("%s is %s", sender, state)
+			}
+			listings = append(listings, listing)
+		}
+
+		json.NewEncoder(w).Encode(listings)
+	})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reaction is a single emoji reaction left on a message.
+type Reaction struct {
+	Sender    string    `json:"sender"`
+	Emoji     string    `json:"emoji"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MessageThread is the full thread context returned by
+// GET /api/messages/{chat_jid}/{message_id}: the message itself, its reactions, and the
+// quoted message it replied to, if any.
+type MessageThread struct {
+	ID              string     `json:"id"`
+	ChatJID         string     `json:"chat_jid"`
+	Sender          string     `json:"sender"`
+	Content         string     `json:"content"`
+	Timestamp       time.Time  `json:"timestamp"`
+	IsFromMe        bool       `json:"is_from_me"`
+	MediaType       string     `json:"media_type,omitempty"`
+	Transcription   string     `json:"transcription,omitempty"`
+	QuotedMessageID string     `json:"quoted_message_id,omitempty"`
+	QuotedSender    string     `json:"quoted_sender,omitempty"`
+	QuotedContent   string     `json:"quoted_content,omitempty"`
+	Reactions       []Reaction `json:"reactions,omitempty"`
+	EditedAt        *time.Time `json:"edited_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+// GetMessageThread loads the full thread context for a single message.
+func (store *MessageStore) GetMessageThread(id, chatJID string) (*MessageThread, error) {
+	var thread MessageThread
+	var reactionsJSON sql.NullString
+	var editedAt, deletedAt sql.NullTime
+
+	err := store.db.QueryRow(`
+		SELECT id, chat_jid, sender, COALESCE(content, ''), timestamp, is_from_me,
+		       COALESCE(media_type, ''), COALESCE(transcription, ''),
+		       COALESCE(reply_to_id, ''), COALESCE(reply_to_sender, ''), COALESCE(quoted_content, ''),
+		       reactions_json, edited_at, deleted_at
+		FROM messages WHERE id = ? AND chat_jid = ?
+	`, id, chatJID).Scan(
+		&thread.ID, &thread.ChatJID, &thread.Sender, &thread.Content, &thread.Timestamp, &thread.IsFromMe,
+		&thread.MediaType, &thread.Transcription,
+		&thread.QuotedMessageID, &thread.QuotedSender, &thread.QuotedContent,
+		&reactionsJSON, &editedAt, &deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if reactionsJSON.Valid && reactionsJSON.String != "" {
+		if err := json.Unmarshal([]byte(reactionsJSON.String), &thread.Reactions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reactions: %v", err)
+		}
+	}
+	if editedAt.Valid {
+		thread.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		thread.DeletedAt = &deletedAt.Time
+	}
+
+	return &thread, nil
+}
+
+// AddReaction upserts sender's reaction on a message; an empty emoji removes it,
+// matching how WhatsApp represents "un-reacting" as a reaction with blank text.
+func (store *MessageStore) AddReaction(id, chatJID, sender, emoji string, timestamp time.Time) error {
+	var reactionsJSON sql.NullString
+	err := store.db.QueryRow(
+		"SELECT reactions_json FROM messages WHERE id = ? AND chat_jid = ?", id, chatJID,
+	).Scan(&reactionsJSON)
+	if err != nil {
+		return err
+	}
+
+	var reactions []Reaction
+	if reactionsJSON.Valid && reactionsJSON.String != "" {
+		if err := json.Unmarshal([]byte(reactionsJSON.String), &reactions); err != nil {
+			return fmt.Errorf("failed to unmarshal reactions: %v", err)
+		}
+	}
+
+	filtered := reactions[:0]
+	for _, r := range reactions {
+		if r.Sender != sender {
+			filtered = append(filtered, r)
+		}
+	}
+	reactions = filtered
+	if emoji != "" {
+		reactions = append(reactions, Reaction{Sender: sender, Emoji: emoji, Timestamp: timestamp})
+	}
+
+	data, err := json.Marshal(reactions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reactions: %v", err)
+	}
+
+	_, err = store.db.Exec(
+		"UPDATE messages SET reactions_json = ? WHERE id = ? AND chat_jid = ?",
+		string(data), id, chatJID,
+	)
+	return err
+}
+
+// EditMessage updates a message's content in place and stamps edited_at, preserving the
+// original row (and its replies/reactions) rather than inserting a new message.
+func (store *MessageStore) EditMessage(id, chatJID, content string, editedAt time.Time) error {
+	_, err := store.db.Exec(
+		"UPDATE messages SET content = ?, edited_at = ? WHERE id = ? AND chat_jid = ?",
+		content, editedAt, id, chatJID,
+	)
+	return err
+}
+
+// handleReaction persists an incoming ReactionMessage.
+func handleReaction(messageStore *MessageStore, chatJID, sender string, reaction *waProto.ReactionMessage, logger waLog.Logger) {
+	key := reaction.GetKey()
+	if key == nil || key.GetID() == "" {
+		logger.Warnf("Received reaction with no target message key in %s", chatJID)
+		return
+	}
+
+	timestamp := time.Now()
+	if ms := reaction.GetSenderTimestampMS(); ms > 0 {
+		timestamp = time.UnixMilli(ms)
+	}
+
+	if err := messageStore.AddReaction(key.GetID(), chatJID, sender, reaction.GetText(), timestamp); err != nil {
+		logger.Warnf("Failed to store reaction on %s in %s: %v", key.GetID(), chatJID, err)
+		return
+	}
+
+	eventBus.Publish(EventKindReaction, map[string]interface{}{
+		"chat_jid":   chatJID,
+		"message_id": key.GetID(),
+		"sender":     sender,
+		"emoji":      reaction.GetText(),
+	})
+}
+
+// handleEdit persists an incoming MESSAGE_EDIT ProtocolMessage.
+func handleEdit(messageStore *MessageStore, chatJID string, protocolMsg *waProto.ProtocolMessage, logger waLog.Logger) {
+	key := protocolMsg.GetKey()
+	if key == nil || key.GetID() == "" {
+		logger.Warnf("Received MESSAGE_EDIT with no target message key in %s", chatJID)
+		return
+	}
+
+	content := extractTextContent(protocolMsg.GetEditedMessage())
+	if err := messageStore.EditMessage(key.GetID(), chatJID, content, time.Now()); err != nil {
+		logger.Warnf("Failed to store edit of %s in %s: %v", key.GetID(), chatJID, err)
+		return
+	}
+
+	eventBus.Publish(EventKindMessage, map[string]interface{}{
+		"chat_jid":   chatJID,
+		"message_id": key.GetID(),
+		"content":    content,
+		"edited":     true,
+	})
+}
+
+// reactRequest is the body for POST /api/react.
+type reactRequest struct {
+	ChatJID   string `json:"chat_jid"`
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// registerReactionHandlers exposes the message-thread lookup and outgoing reaction send.
+func registerReactionHandlers(client *whatsmeow.Client, messageStore *MessageStore, logger waLog.Logger) {
+	http.HandleFunc("/api/messages/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/api/messages/")
+		if path == "recent" {
+			http.NotFound(w, r)
+			return
+		}
+
+		chatJID, messageID, hasMessageID := strings.Cut(path, "/")
+		if chatJID == "" || !hasMessageID || messageID == "" {
+			http.Error(w, "Expected /api/messages/{chat_jid}/{message_id}", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		thread, err := messageStore.GetMessageThread(messageID, chatJID)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "message not found"})
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(thread)
+	})
+
+	http.HandleFunc("/api/react", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var req reactRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+			return
+		}
+		if req.ChatJID == "" || req.MessageID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "chat_jid and message_id are required"})
+			return
+		}
+
+		sender, _, _, err := messageStore.GetMessageForQuote(req.MessageID, req.ChatJID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "target message not found"})
+			return
+		}
+
+		chatJID, err := types.ParseJID(req.ChatJID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid chat JID: %v", err)})
+			return
+		}
+		senderJID, err := types.ParseJID(sender)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid target sender JID: %v", err)})
+			return
+		}
+
+		_, err = client.SendMessage(context.Background(), chatJID, &waProto.Message{
+			ReactionMessage: &waProto.ReactionMessage{
+				Key: &waProto.MessageKey{
+					RemoteJID: proto.String(chatJID.String()),
+					FromMe:    proto.Bool(senderJID.User == client.Store.ID.User),
+					ID:        proto.String(req.MessageID),
+				},
+				Text:              proto.String(req.Emoji),
+				SenderTimestampMS: proto.Int64(time.Now().UnixMilli()),
+			},
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := messageStore.AddReaction(req.MessageID, req.ChatJID, client.Store.ID.String(), req.Emoji, time.Now()); err != nil {
+			logger.Warnf("Failed to store outgoing reaction on %s: %v", req.MessageID, err)
+		}
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+const (
+	reconnectMinBackoff      = 2 * time.Second
+	reconnectMaxBackoff      = 5 * time.Minute
+	reconnectBackoffFactor   = 2
+	reconnectErrorHistoryCap = 10
+)
+
+// ConnectionState is the supervisor's view of the client's connection lifecycle, surfaced
+// to callers (CLI, /api/health) instead of making them infer it from raw IsConnected().
+type ConnectionState string
+
+const (
+	StateConnecting   ConnectionState = "connecting"
+	StateConnected    ConnectionState = "connected"
+	StateDisconnected ConnectionState = "disconnected"
+	StateLoggedOut    ConnectionState = "logged_out"
+)
+
+// ConnectionStateEvent is emitted on ReconnectSupervisor.StateChanged every time the
+// connection lifecycle advances, so a CLI can surface reconnect status live instead of
+// polling /api/health.
+type ConnectionStateEvent struct {
+	State     ConnectionState
+	Err       error
+	NextRetry time.Time
+}
+
+// ReconnectSupervisor tracks connection health and drives exponential-backoff
+// reconnection, the same reconnect loop the matterbridge whatsapp bridge runs, so
+// orchestrators can distinguish "process alive but WhatsApp disconnected" from healthy.
+type ReconnectSupervisor struct {
+	mu            sync.Mutex
+	state         ConnectionState
+	lastConnected time.Time
+	attempts      int
+	errorHistory  []string
+	reconnecting  bool
+	loggedOut     bool
+
+	// StateChanged emits every connection lifecycle transition; sends are non-blocking so a
+	// slow or absent consumer never stalls the reconnect loop.
+	StateChanged chan ConnectionStateEvent
+}
+
+// reconnectSupervisor is the process-wide tracker, queried by /api/health.
+var reconnectSupervisor = &ReconnectSupervisor{
+	state:        StateDisconnected,
+	StateChanged: make(chan ConnectionStateEvent, 16),
+}
+
+func (s *ReconnectSupervisor) emit(evt ConnectionStateEvent) {
+	select {
+	case s.StateChanged <- evt:
+	default:
+	}
+}
+
+func (s *ReconnectSupervisor) recordConnected() {
+	s.mu.Lock()
+	s.lastConnected = time.Now()
+	s.attempts = 0
+	s.reconnecting = false
+	s.loggedOut = false
+	s.state = StateConnected
+	s.mu.Unlock()
+
+	s.emit(ConnectionStateEvent{State: StateConnected})
+}
+
+func (s *ReconnectSupervisor) recordError(err error) {
+	s.mu.Lock()
+	s.errorHistory = append(s.errorHistory, fmt.Sprintf("%s: %v", time.Now().Format(time.RFC3339), err))
+	if len(s.errorHistory) > reconnectErrorHistoryCap {
+		s.errorHistory = s.errorHistory[len(s.errorHistory)-reconnectErrorHistoryCap:]
+	}
+	s.state = StateDisconnected
+	s.mu.Unlock()
+
+	s.emit(ConnectionStateEvent{State: StateDisconnected, Err: err})
+}
+
+// recordLoggedOut marks the session as logged out: no further reconnect attempts will be
+// made until a fresh QR/pair-code auth flow creates a new session.
+func (s *ReconnectSupervisor) recordLoggedOut() {
+	s.mu.Lock()
+	s.loggedOut = true
+	s.reconnecting = false
+	s.state = StateLoggedOut
+	s.mu.Unlock()
+
+	s.emit(ConnectionStateEvent{State: StateLoggedOut})
+}
+
+// CanSend reports whether the supervisor currently believes the client is usable for
+// sending messages or issuing history sync requests, instead of callers checking
+// client.IsConnected() directly and missing the logged-out case.
+func (s *ReconnectSupervisor) CanSend() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state == StateConnected
+}
+
+// currentState returns the supervisor's current ConnectionState for /api/health.
+func (s *ReconnectSupervisor) currentState() ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// snapshot returns the current health fields for /api/health.
+func (s *ReconnectSupervisor) snapshot() (lastConnected time.Time, attempts int, errorHistory []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastConnected, s.attempts, append([]string(nil), s.errorHistory...)
+}
+
+// scheduleReconnect retries client.Connect with jittered exponential backoff (min 2s, max
+// 5m, factor 2) until it succeeds, bailing out early if whatsmeow reconnects on its own
+// first or if the session has been logged out. Calling it while a reconnect loop is
+// already running, or after a logout, is a no-op.
+func (s *ReconnectSupervisor) scheduleReconnect(client *whatsmeow.Client, logger waLog.Logger) {
+	s.mu.Lock()
+	if s.reconnecting || s.loggedOut {
+		s.mu.Unlock()
+		return
+	}
+	s.reconnecting = true
+	s.mu.Unlock()
+
+	go func() {
+		backoff := reconnectMinBackoff
+		for {
+			s.mu.Lock()
+			loggedOut := s.loggedOut
+			s.mu.Unlock()
+			if loggedOut || client.IsConnected() {
+				return
+			}
+
+			s.mu.Lock()
+			s.attempts++
+			attempt := s.attempts
+			s.mu.Unlock()
+
+			wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff)/2+1))
+			s.emit(ConnectionStateEvent{State: StateDisconnected, NextRetry: time.Now().Add(wait)})
+			logger.Warnf("Reconnect attempt %d in %s", attempt, wait)
+			time.Sleep(wait)
+
+			if client.IsConnected() {
+				return
+			}
+
+			s.mu.Lock()
+			s.state = StateConnecting
+			s.mu.Unlock()
+			s.emit(ConnectionStateEvent{State: StateConnecting})
+			setAuthState("connecting", "", "")
+
+			if err := client.Connect(); err != nil {
+				s.recordError(err)
+				logger.Warnf("Reconnect attempt %d failed: %v", attempt, err)
+				backoff *= reconnectBackoffFactor
+				if backoff > reconnectMaxBackoff {
+					backoff = reconnectMaxBackoff
+				}
+				continue
+			}
+
+			logger.Infof("Reconnected to WhatsApp after %d attempt(s)", attempt)
+			return
+		}
+	}()
+}
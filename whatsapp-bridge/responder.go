@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// ResponderConfig describes one auto-reply hook: which chat(s) it watches, what
+// triggers it, and the HTTP endpoint it forwards matching messages to.
+type ResponderConfig struct {
+	ChatJID            string `json:"chat_jid"` // "*" matches every chat
+	Trigger            string `json:"trigger"`  // "all", "mention", or "regex"
+	Pattern            string `json:"pattern,omitempty"` // used when Trigger == "regex"
+	Endpoint           string `json:"endpoint"`
+	DryRun             bool   `json:"dry_run,omitempty"`
+	RateLimitPerMinute int    `json:"rate_limit_per_minute,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+var (
+	responderMu sync.RWMutex
+	responders  []ResponderConfig
+
+	responderRateMu sync.Mutex
+	responderLastSent = make(map[string]time.Time) // keyed by chatJID+endpoint
+)
+
+// responderMatches reports whether content from a non-self sender should trigger cfg.
+func (cfg *ResponderConfig) responderMatches(client *whatsmeow.Client, chatJID, content string) bool {
+	if cfg.ChatJID != "*" && cfg.ChatJID != chatJID {
+		return false
+	}
+
+	switch cfg.Trigger {
+	case "all":
+		return true
+	case "mention":
+		return client.Store.ID != nil && regexp.MustCompile(`@?`+regexp.QuoteMeta(client.Store.ID.User)).MatchString(content)
+	case "regex":
+		if cfg.pattern == nil {
+			return false
+		}
+		return cfg.pattern.MatchString(content)
+	default:
+		return false
+	}
+}
+
+// allowedByRateLimit reports whether cfg may fire again for chatJID right now, advancing
+// the last-sent timestamp if so.
+func (cfg *ResponderConfig) allowedByRateLimit(chatJID string) bool {
+	if cfg.RateLimitPerMinute <= 0 {
+		return true
+	}
+
+	key := chatJID + "|" + cfg.Endpoint
+	minInterval := time.Minute / time.Duration(cfg.RateLimitPerMinute)
+
+	responderRateMu.Lock()
+	defer responderRateMu.Unlock()
+
+	if last, ok := responderLastSent[key]; ok && time.Since(last) < minInterval {
+		return false
+	}
+	responderLastSent[key] = time.Now()
+	return true
+}
+
+// responderRequest is the JSON payload POSTed to a responder's endpoint.
+type responderRequest struct {
+	ChatJID string `json:"chat_jid"`
+	Sender  string `json:"sender"`
+	Content string `json:"content"`
+}
+
+// responderResponse is the expected JSON shape returned by a responder's endpoint.
+type responderResponse struct {
+	Reply string `json:"reply"`
+}
+
+// dispatchResponders forwards an incoming message to every matching responder and sends
+// back whatever reply each one returns, unless it's configured for dry-run.
+func dispatchResponders(client *whatsmeow.Client, messageStore *MessageStore, chatJID, sender, content string, logger waLog.Logger) {
+	if content == "" {
+		return
+	}
+
+	responderMu.RLock()
+	matched := make([]ResponderConfig, 0, len(responders))
+	for _, cfg := range responders {
+		if cfg.responderMatches(client, chatJID, content) {
+			matched = append(matched, cfg)
+		}
+	}
+	responderMu.RUnlock()
+
+	for _, cfg := range matched {
+		if !cfg.allowedByRateLimit(chatJID) {
+			logger.Infof("Responder %s rate-limited for %s", cfg.Endpoint, chatJID)
+			continue
+		}
+		go runResponder(client, messageStore, cfg, chatJID, sender, content, logger)
+	}
+}
+
+func runResponder(client *whatsmeow.Client, messageStore *MessageStore, cfg ResponderConfig, chatJID, sender, content string, logger waLog.Logger) {
+	payload, err := json.Marshal(responderRequest{ChatJID: chatJID, Sender: sender, Content: content})
+	if err != nil {
+		logger.Warnf("Failed to marshal responder request: %v", err)
+		return
+	}
+
+	resp, err := http.Post(cfg.Endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		logger.Warnf("Responder %s request failed: %v", cfg.Endpoint, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		logger.Warnf("Responder %s returned status %d", cfg.Endpoint, resp.StatusCode)
+		return
+	}
+
+	var result responderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Reply == "" {
+		return
+	}
+
+	if cfg.DryRun {
+		logger.Infof("[dry-run] Responder %s would reply to %s: %s", cfg.Endpoint, chatJID, result.Reply)
+		return
+	}
+
+	if ok, errMsg := sendWhatsAppMessage(client, messageStore, chatJID, result.Reply, "", ""); !ok {
+		logger.Warnf("Failed to send responder reply to %s: %s", chatJID, errMsg)
+	}
+}
+
+// registerResponderHandlers exposes GET/PUT /api/responders to view and replace the
+// configured auto-responders at runtime.
+func registerResponderHandlers() {
+	http.HandleFunc("/api/responders", func(w http.ResponseWriter, r *http.Request) {
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodGet:
+			responderMu.RLock()
+			cfgs := append([]ResponderConfig(nil), responders...)
+			responderMu.RUnlock()
+			json.NewEncoder(w).Encode(cfgs)
+		case http.MethodPut:
+			var cfgs []ResponderConfig
+			if err := json.NewDecoder(r.Body).Decode(&cfgs); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+				return
+			}
+
+			for i := range cfgs {
+				if cfgs[i].Trigger == "regex" {
+					pattern, err := regexp.Compile(cfgs[i].Pattern)
+					if err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid pattern %q: %v", cfgs[i].Pattern, err)})
+						return
+					}
+					cfgs[i].pattern = pattern
+				}
+			}
+
+			responderMu.Lock()
+			responders = cfgs
+			responderMu.Unlock()
+			json.NewEncoder(w).Encode(cfgs)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// sessionManager is the process-wide multi-device host, set up once in startRESTServer
+// alongside the other global bridge state (event bus, history sync manager).
+var sessionManager *SessionManager
+
+// Session is one logged-in device hosted by a SessionManager: its own whatsmeow client,
+// its own message store (a separate SQLite file under the device's JID), and its own
+// history sync manager, so concurrent accounts never share state or clobber each other's
+// messages and downloads.
+type Session struct {
+	JID          types.JID
+	Client       *whatsmeow.Client
+	MessageStore *MessageStore
+	HistorySync  *HistorySyncManager
+
+	cancel context.CancelFunc
+}
+
+// SessionManager hosts N logged-in whatsmeow devices against a single sqlstore.Container,
+// for multi-account/multi-device deployments where the single global client/messageStore
+// main() wires up for one device isn't enough.
+//
+// handleMessage, handleHistorySync/HistorySyncManager.Ingest, and MessageStore.StoreMessage
+// already take the client and message store as parameters rather than reading package
+// globals, so routing those three call sites per-session (the ones the originating request
+// calls out explicitly) falls out of giving each Session its own instances below. Moving
+// every other subsystem (responders, the platform bridge, filters) onto a per-session
+// handle instead of the process-wide globals main() wires up is a larger migration left for
+// a follow-up change.
+type SessionManager struct {
+	container *sqlstore.Container
+	baseDir   string
+	logger    waLog.Logger
+
+	mu       sync.RWMutex
+	sessions map[string]*Session // keyed by JID string
+
+	pairingMu     sync.RWMutex
+	pairingStatus string // "", "waiting_for_qr", "connected", "timeout", "error"
+	pairingQR     string
+}
+
+// NewSessionManager creates a manager backed by container, storing each device's messages
+// under baseDir/<jid>/messages.db.
+func NewSessionManager(container *sqlstore.Container, baseDir string, logger waLog.Logger) *SessionManager {
+	return &SessionManager{
+		container: container,
+		baseDir:   baseDir,
+		logger:    logger,
+		sessions:  make(map[string]*Session),
+	}
+}
+
+// Clients returns every currently logged-in device's whatsmeow client.
+func (m *SessionManager) Clients() []*whatsmeow.Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clients := make([]*whatsmeow.Client, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		clients = append(clients, s.Client)
+	}
+	return clients
+}
+
+// Session returns the managed session for jid, if it's currently logged in.
+func (m *SessionManager) Session(jid types.JID) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[jid.String()]
+	return s, ok
+}
+
+// sessionDir returns this device's dedicated store directory.
+func (m *SessionManager) sessionDir(jid types.JID) string {
+	return filepath.Join(m.baseDir, strings.ReplaceAll(jid.String(), ":", "_"))
+}
+
+// AddDevice starts pairing a fresh device via QR code. It returns the whatsmeow QR event
+// channel to display to the caller and a second channel that receives the device's JID
+// exactly once, after the "success" event arrives and the session has been registered;
+// jidChan is closed without a value if pairing fails or times out.
+func (m *SessionManager) AddDevice(ctx context.Context) (qrChan <-chan whatsmeow.QRChannelItem, jidChan <-chan types.JID, err error) {
+	deviceStore := m.container.NewDevice()
+	client := whatsmeow.NewClient(deviceStore, m.logger)
+
+	rawQRChan, err := client.GetQRChannel(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get QR channel: %v", err)
+	}
+	if err := client.Connect(); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect new device: %v", err)
+	}
+
+	outQR := make(chan whatsmeow.QRChannelItem, 8)
+	outJID := make(chan types.JID, 1)
+
+	go func() {
+		defer close(outQR)
+		defer close(outJID)
+
+		for evt := range rawQRChan {
+			outQR <- evt
+			if evt.Event != "success" {
+				continue
+			}
+			if client.Store.ID == nil {
+				m.logger.Errorf("QR pairing reported success but device has no JID")
+				return
+			}
+			if err := m.register(client); err != nil {
+				m.logger.Errorf("Failed to register paired device: %v", err)
+				return
+			}
+			outJID <- *client.Store.ID
+			return
+		}
+	}()
+
+	return outQR, outJID, nil
+}
+
+// LoadExistingSessions reattaches every already-paired device found in the container, so a
+// device added via POST /api/devices survives a process restart instead of only living in
+// the in-memory sessions map until NewSessionManager is called again. Devices that were
+// never paired (no JID) are skipped; no new device is created here.
+func (m *SessionManager) LoadExistingSessions(ctx context.Context) error {
+	devices, err := m.container.GetAllDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list existing devices: %v", err)
+	}
+
+	for _, deviceStore := range devices {
+		if deviceStore.ID == nil {
+			continue
+		}
+
+		client := whatsmeow.NewClient(deviceStore, m.logger)
+		if err := m.register(client); err != nil {
+			m.logger.Errorf("Failed to restore session for %s: %v", deviceStore.ID, err)
+			continue
+		}
+		if err := client.Connect(); err != nil {
+			m.logger.Errorf("Failed to connect restored session for %s: %v", deviceStore.ID, err)
+		}
+	}
+	return nil
+}
+
+// register wires up a message store, history sync manager, and event loop for a newly
+// paired (or otherwise already-authenticated) client, and tracks it for Clients/Session.
+func (m *SessionManager) register(client *whatsmeow.Client) error {
+	jid := *client.Store.ID
+	dir := m.sessionDir(jid)
+
+	store, err := NewMessageStoreAt(dir)
+	if err != nil {
+		return fmt.Errorf("failed to create message store for %s: %v", jid, err)
+	}
+
+	hsManager, err := NewHistorySyncManager(client, store, m.logger)
+	if err != nil {
+		store.Close()
+		return fmt.Errorf("failed to create history sync manager for %s: %v", jid, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &Session{JID: jid, Client: client, MessageStore: store, HistorySync: hsManager, cancel: cancel}
+
+	m.mu.Lock()
+	m.sessions[jid.String()] = session
+	m.mu.Unlock()
+
+	m.startEventLoop(ctx, session)
+	m.logger.Infof("Registered session for device %s", jid)
+	return nil
+}
+
+// startEventLoop routes this session's messages and history sync pages through its own
+// store and history sync manager instead of any package-level global.
+func (m *SessionManager) startEventLoop(ctx context.Context, session *Session) {
+	transcriptionPool, err := StartTranscriptionWorkerPool(session.Client, session.MessageStore, NewTranscriberFromEnv(), defaultTranscriptionWorkers, m.logger)
+	if err != nil {
+		m.logger.Errorf("Failed to start transcription pool for %s: %v", session.JID, err)
+		return
+	}
+
+	session.Client.AddEventHandler(func(evt interface{}) {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		switch v := evt.(type) {
+		case *events.Message:
+			handleMessage(session.Client, session.MessageStore, transcriptionPool, v, m.logger)
+		case *events.HistorySync:
+			session.HistorySync.Ingest(session.Client, v, m.logger)
+		}
+	})
+}
+
+// setPairingState records the latest AddDevice progress so registerSessionHandlers can
+// expose it via polling, the same pattern the single-device flow uses via setAuthState.
+func (m *SessionManager) setPairingState(status, qr string) {
+	m.pairingMu.Lock()
+	m.pairingStatus = status
+	m.pairingQR = qr
+	m.pairingMu.Unlock()
+}
+
+// PairingState returns the status and QR code (if any) of the most recent AddDevice call.
+func (m *SessionManager) PairingState() (status, qr string) {
+	m.pairingMu.RLock()
+	defer m.pairingMu.RUnlock()
+	return m.pairingStatus, m.pairingQR
+}
+
+// StartPairing kicks off AddDevice in the background and updates PairingState as the QR
+// channel progresses, so registerSessionHandlers' POST /api/devices can return immediately
+// instead of blocking the request on a scan.
+func (m *SessionManager) StartPairing(ctx context.Context) error {
+	qrChan, _, err := m.AddDevice(ctx)
+	if err != nil {
+		m.setPairingState("error", "")
+		return err
+	}
+
+	m.setPairingState("waiting_for_qr", "")
+
+	go func() {
+		for evt := range qrChan {
+			switch evt.Event {
+			case "code":
+				m.setPairingState("waiting_for_qr", evt.Code)
+			case "success":
+				m.setPairingState("connected", "")
+			case "timeout":
+				m.setPairingState("timeout", "")
+			}
+		}
+	}()
+
+	return nil
+}
+
+// ListDevices returns the JID of every currently logged-in device.
+func (m *SessionManager) ListDevices() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jids := make([]string, 0, len(m.sessions))
+	for jid := range m.sessions {
+		jids = append(jids, jid)
+	}
+	return jids
+}
+
+// registerSessionHandlers exposes GET/POST /api/devices to list devices and pair a new one
+// by QR code, GET /api/devices/pairing to poll an in-progress pairing, and
+// DELETE /api/devices/{jid} to log one out.
+func registerSessionHandlers(manager *SessionManager, logger waLog.Logger) {
+	http.HandleFunc("/api/devices", func(w http.ResponseWriter, r *http.Request) {
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string][]string{"devices": manager.ListDevices()})
+		case http.MethodPost:
+			if err := manager.StartPairing(context.Background()); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"status": "pairing_started"})
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/api/devices/pairing", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		status, qr := manager.PairingState()
+		resp := map[string]string{"status": status}
+		if qr != "" {
+			resp["qr_code"] = qr
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	http.HandleFunc("/api/devices/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		jidStr := strings.TrimPrefix(r.URL.Path, "/api/devices/")
+		jid, err := types.ParseJID(jidStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid device JID: %v", err)})
+			return
+		}
+
+		if err := manager.RemoveDevice(jid); err != nil {
+			logger.Warnf("Failed to remove device %s: %v", jid, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	})
+}
+
+// RemoveDevice logs a device out, tears down its event loop, and drops its store directory
+// so a removed account's messages don't linger on disk.
+func (m *SessionManager) RemoveDevice(jid types.JID) error {
+	m.mu.Lock()
+	session, ok := m.sessions[jid.String()]
+	if ok {
+		delete(m.sessions, jid.String())
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no session for %s", jid)
+	}
+
+	session.cancel()
+	if err := session.Client.Logout(context.Background()); err != nil {
+		m.logger.Warnf("Failed to log out %s cleanly: %v", jid, err)
+	}
+	session.Client.Disconnect()
+	if err := session.MessageStore.Close(); err != nil {
+		m.logger.Warnf("Failed to close message store for %s: %v", jid, err)
+	}
+
+	if err := os.RemoveAll(m.sessionDir(jid)); err != nil {
+		return fmt.Errorf("failed to remove store directory for %s: %v", jid, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	transcriberKind     = getEnv("TRANSCRIBER", "local")
+	whisperServerURL    = getEnv("WHISPER_SERVER_URL", "http://127.0.0.1:8081")
+	openAITranscribeURL = getEnv("OPENAI_TRANSCRIBE_URL", "https://api.openai.com/v1/audio/transcriptions")
+	openAIAPIKey        = os.Getenv("OPENAI_API_KEY")
+	openAIModel         = getEnv("OPENAI_TRANSCRIBE_MODEL", "whisper-1")
+	transcribeLanguage  = os.Getenv("TRANSCRIBE_LANGUAGE") // empty means auto-detect where supported
+)
+
+// Transcriber converts an audio file on disk into text, detecting the spoken language
+// where the backend supports it.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioPath string) (text, language string, err error)
+}
+
+// NewTranscriberFromEnv builds the Transcriber selected by the TRANSCRIBER env var
+// (local|whisper-server|openai), defaulting to the local whisper-cli backend.
+func NewTranscriberFromEnv() Transcriber {
+	switch transcriberKind {
+	case "whisper-server":
+		return &whisperServerTranscriber{endpoint: whisperServerURL}
+	case "openai":
+		return &openAITranscriber{
+			endpoint: openAITranscribeURL,
+			apiKey:   openAIAPIKey,
+			model:    openAIModel,
+			language: transcribeLanguage,
+		}
+	default:
+		return &localTranscriber{}
+	}
+}
+
+// localTranscriber converts the audio to 16kHz mono WAV with ffmpeg and shells out to whisper-cli.
+type localTranscriber struct{}
+
+var whisperLanguageRe = regexp.MustCompile(`\[([a-z]{2})\]`)
+
+func (t *localTranscriber) Transcribe(ctx context.Context, audioPath string) (string, string, error) {
+	if _, err := os.Stat(whisperBinPath); os.IsNotExist(err) {
+		return "", "", fmt.Errorf("whisper-cli not found at %s, install with: brew install whisper-cpp", whisperBinPath)
+	}
+	if _, err := os.Stat(whisperModelPath); os.IsNotExist(err) {
+		return "", "", fmt.Errorf("whisper model not found at %s", whisperModelPath)
+	}
+
+	wavPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + "_transcribe.wav"
+	defer os.Remove(wavPath)
+
+	convertCmd := exec.CommandContext(ctx, ffmpegBinPath,
+		"-i", audioPath,
+		"-ar", "16000",
+		"-ac", "1",
+		"-c:a", "pcm_s16le",
+		"-y",
+		wavPath,
+	)
+	convertOut, err := convertCmd.CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("ffmpeg conversion failed: %v, output: %s", err, string(convertOut))
+	}
+
+	whisperArgs := []string{"-m", whisperModelPath, "-f", wavPath, "--no-timestamps"}
+	if transcribeLanguage != "" {
+		whisperArgs = append(whisperArgs, "-l", transcribeLanguage)
+	} else {
+		whisperArgs = append(whisperArgs, "-l", "auto", "--print-special")
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	whisperCmd := exec.CommandContext(cmdCtx, whisperBinPath, whisperArgs...)
+
+	var stdout, stderr bytes.Buffer
+	whisperCmd.Stdout = &stdout
+	whisperCmd.Stderr = &stderr
+
+	if err := whisperCmd.Run(); err != nil {
+		return "", "", fmt.Errorf("whisper-cli failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	language := ""
+	if match := whisperLanguageRe.FindStringSubmatch(stderr.String()); len(match) == 2 {
+		language = match[1]
+	} else if transcribeLanguage != "" {
+		language = transcribeLanguage
+	}
+
+	transcription := strings.TrimSpace(stdout.String())
+	return transcription, language, nil
+}
+
+// whisperServerTranscriber posts audio to a running whisper.cpp server (HTTP /inference mode).
+type whisperServerTranscriber struct {
+	endpoint string
+}
+
+func (t *whisperServerTranscriber) Transcribe(ctx context.Context, audioPath string) (string, string, error) {
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open audio file: %v", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(audioPath))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build multipart request: %v", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", "", fmt.Errorf("failed to copy audio into request: %v", err)
+	}
+	writer.WriteField("response_format", "json")
+	if transcribeLanguage != "" {
+		writer.WriteField("language", transcribeLanguage)
+	}
+	if err := writer.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to finalize multipart request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(t.endpoint, "/")+"/inference", &body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("whisper-server request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", "", fmt.Errorf("whisper-server returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", fmt.Errorf("failed to decode whisper-server response: %v", err)
+	}
+
+	return strings.TrimSpace(result.Text), result.Language, nil
+}
+
+// openAITranscriber posts audio to the OpenAI /v1/audio/transcriptions endpoint.
+type openAITranscriber struct {
+	endpoint string
+	apiKey   string
+	model    string
+	language string
+}
+
+func (t *openAITranscriber) Transcribe(ctx context.Context, audioPath string) (string, string, error) {
+	if t.apiKey == "" {
+		return "", "", fmt.Errorf("OPENAI_API_KEY is not set")
+	}
+
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open audio file: %v", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(audioPath))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build multipart request: %v", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", "", fmt.Errorf("failed to copy audio into request: %v", err)
+	}
+	writer.WriteField("model", t.model)
+	writer.WriteField("response_format", "verbose_json")
+	if t.language != "" {
+		writer.WriteField("language", t.language)
+	}
+	if err := writer.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to finalize multipart request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, &body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("OpenAI request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", "", fmt.Errorf("OpenAI returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", fmt.Errorf("failed to decode OpenAI response: %v", err)
+	}
+
+	return strings.TrimSpace(result.Text), result.Language, nil
+}
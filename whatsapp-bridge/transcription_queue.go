@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+const defaultTranscriptionWorkers = 2
+
+// TranscriptionJob is a pending voice note awaiting transcription.
+type TranscriptionJob struct {
+	ID        int64
+	MessageID string
+	ChatJID   string
+	IsFromMe  bool
+}
+
+// createTranscriptionQueueTable creates the persistent queue backing the worker pool,
+// so pending voice notes survive a restart instead of being lost with an in-memory channel.
+func createTranscriptionQueueTable(store *MessageStore) error {
+	_, err := store.db.Exec(`
+		CREATE TABLE IF NOT EXISTS transcription_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMP NOT NULL,
+			error TEXT
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Migration: add language column if it doesn't exist (for existing databases)
+	if _, err := store.db.Exec(`ALTER TABLE messages ADD COLUMN language TEXT`); err != nil && !isDuplicateColumnErr(err) {
+		return err
+	}
+
+	// Migration: add is_from_me so a transcribed voice note we sent ourselves doesn't
+	// re-trigger the auto-responder (see process).
+	if _, err := store.db.Exec(`ALTER TABLE transcription_queue ADD COLUMN is_from_me BOOLEAN NOT NULL DEFAULT 0`); err != nil && !isDuplicateColumnErr(err) {
+		return err
+	}
+	return nil
+}
+
+// EnqueueTranscriptionJob persists a pending transcription job.
+func (store *MessageStore) EnqueueTranscriptionJob(messageID, chatJID string, isFromMe bool) (int64, error) {
+	res, err := store.db.Exec(
+		"INSERT INTO transcription_queue (message_id, chat_jid, status, created_at, is_from_me) VALUES (?, ?, 'pending', ?, ?)",
+		messageID, chatJID, time.Now(), isFromMe,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// PendingTranscriptionJobs returns jobs that were never completed, used to resume
+// a queue left over from before a restart.
+func (store *MessageStore) PendingTranscriptionJobs() ([]TranscriptionJob, error) {
+	rows, err := store.db.Query("SELECT id, message_id, chat_jid, is_from_me FROM transcription_queue WHERE status = 'pending' ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []TranscriptionJob
+	for rows.Next() {
+		var job TranscriptionJob
+		if err := rows.Scan(&job.ID, &job.MessageID, &job.ChatJID, &job.IsFromMe); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (store *MessageStore) markTranscriptionJobDone(id int64) error {
+	_, err := store.db.Exec("UPDATE transcription_queue SET status = 'done' WHERE id = ?", id)
+	return err
+}
+
+func (store *MessageStore) markTranscriptionJobFailed(id int64, jobErr error) error {
+	_, err := store.db.Exec("UPDATE transcription_queue SET status = 'failed', error = ? WHERE id = ?", jobErr.Error(), id)
+	return err
+}
+
+// StoreTranscriptionWithLanguage stores both the transcript and detected language.
+func (store *MessageStore) StoreTranscriptionWithLanguage(id, chatJID, transcription, language string) error {
+	_, err := store.db.Exec(
+		"UPDATE messages SET transcription = ?, language = ? WHERE id = ? AND chat_jid = ?",
+		transcription, language, id, chatJID,
+	)
+	return err
+}
+
+// TranscriptionWorkerPool runs a bounded number of workers draining the persistent
+// transcription_queue table, so audio-heavy chats can't spawn unbounded goroutines.
+type TranscriptionWorkerPool struct {
+	client       *whatsmeow.Client
+	messageStore *MessageStore
+	transcriber  Transcriber
+	logger       waLog.Logger
+	jobs         chan TranscriptionJob
+}
+
+// StartTranscriptionWorkerPool creates the queue table, launches `workers` goroutines,
+// and re-enqueues any jobs left pending from a previous run.
+func StartTranscriptionWorkerPool(client *whatsmeow.Client, messageStore *MessageStore, transcriber Transcriber, workers int, logger waLog.Logger) (*TranscriptionWorkerPool, error) {
+	if err := createTranscriptionQueueTable(messageStore); err != nil {
+		return nil, fmt.Errorf("failed to create transcription queue table: %v", err)
+	}
+	if workers <= 0 {
+		workers = defaultTranscriptionWorkers
+	}
+
+	pool := &TranscriptionWorkerPool{
+		client:       client,
+		messageStore: messageStore,
+		transcriber:  transcriber,
+		logger:       logger,
+		jobs:         make(chan TranscriptionJob, 64),
+	}
+
+	for i := 0; i < workers; i++ {
+		go pool.worker()
+	}
+
+	pending, err := messageStore.PendingTranscriptionJobs()
+	if err != nil {
+		logger.Warnf("Failed to load pending transcription jobs: %v", err)
+	} else if len(pending) > 0 {
+		logger.Infof("Resuming %d pending transcription job(s)", len(pending))
+		for _, job := range pending {
+			pool.jobs <- job
+		}
+	}
+
+	return pool, nil
+}
+
+// Enqueue persists and schedules a transcription job for the given message. isFromMe is
+// carried through to process so a transcribed voice note the bot itself sent doesn't
+// re-trigger the auto-responder.
+func (p *TranscriptionWorkerPool) Enqueue(messageID, chatJID string, isFromMe bool) {
+	id, err := p.messageStore.EnqueueTranscriptionJob(messageID, chatJID, isFromMe)
+	if err != nil {
+		p.logger.Warnf("Failed to enqueue transcription job for %s: %v", messageID, err)
+		return
+	}
+	p.jobs <- TranscriptionJob{ID: id, MessageID: messageID, ChatJID: chatJID, IsFromMe: isFromMe}
+}
+
+func (p *TranscriptionWorkerPool) worker() {
+	for job := range p.jobs {
+		p.process(job)
+	}
+}
+
+func (p *TranscriptionWorkerPool) process(job TranscriptionJob) {
+	p.logger.Infof("Transcribing voice note %s in %s...", job.MessageID, job.ChatJID)
+
+	success, _, _, audioPath, err := downloadMedia(p.client, p.messageStore, job.MessageID, job.ChatJID)
+	if !success || err != nil {
+		p.logger.Warnf("Failed to download audio for transcription: %v", err)
+		p.markFailed(job, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	text, language, err := p.transcriber.Transcribe(ctx, audioPath)
+	if err != nil {
+		p.logger.Warnf("Failed to transcribe audio: %v", err)
+		p.markFailed(job, err)
+		return
+	}
+
+	if text != "" {
+		if err := p.messageStore.StoreTranscriptionWithLanguage(job.MessageID, job.ChatJID, text, language); err != nil {
+			p.logger.Warnf("Failed to store transcription: %v", err)
+			p.markFailed(job, err)
+			return
+		}
+		p.logger.Infof("Transcribed voice note %s (%s): %s", job.MessageID, language, text)
+
+		eventBus.Publish(EventKindTranscriptionReady, map[string]interface{}{
+			"message_id":    job.MessageID,
+			"chat_jid":      job.ChatJID,
+			"transcription": text,
+			"language":      language,
+		})
+
+		if !job.IsFromMe {
+			if sender, _, _, err := p.messageStore.GetMessageForQuote(job.MessageID, job.ChatJID); err == nil {
+				dispatchResponders(p.client, p.messageStore, job.ChatJID, sender, text, p.logger)
+			}
+		}
+	}
+
+	if err := p.messageStore.markTranscriptionJobDone(job.ID); err != nil {
+		p.logger.Warnf("Failed to mark transcription job %d done: %v", job.ID, err)
+	}
+}
+
+func (p *TranscriptionWorkerPool) markFailed(job TranscriptionJob, err error) {
+	if err == nil {
+		err = fmt.Errorf("unknown error")
+	}
+	if mErr := p.messageStore.markTranscriptionJobFailed(job.ID, err); mErr != nil {
+		p.logger.Warnf("Failed to mark transcription job %d failed: %v", job.ID, mErr)
+	}
+}
+
+func isDuplicateColumnErr(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "duplicate column")
+}
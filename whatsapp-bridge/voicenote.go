@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// transcodeToOggOpus converts arbitrary audio (wav, mp3, m4a, already-Ogg-Opus, ...) into
+// mono 16kHz Ogg Opus via an external ffmpeg invocation, the same transcode WhatsApp's own
+// clients run before sending a voice note. ffmpeg sniffs the input format itself, so every
+// input is always run through it rather than trusting the caller-supplied mimeType to skip
+// the transcode: a mislabeled or non-conforming (e.g. stereo/48kHz) "already Ogg Opus" file
+// would otherwise be sent without the mono/16kHz normalization voice notes require.
+func transcodeToOggOpus(ctx context.Context, audio io.Reader, mimeType string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-i", "pipe:0",
+		"-ac", "1",
+		"-ar", "16000",
+		"-c:a", "libopus",
+		"-f", "ogg",
+		"pipe:1",
+	)
+	cmd.Stdin = audio
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg transcode failed: %v (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// SendVoiceNote transcodes arbitrary audio to mono 16kHz Ogg Opus, derives real duration
+// and waveform metadata via analyzeOggOpus (the same routine /api/send uses for .ogg media), uploads
+// and sends it as a WhatsApp PTT voice note, and stores the sent message locally using the
+// same mediaType conventions downloadMedia/extractMediaInfo use on the receive path.
+func SendVoiceNote(ctx context.Context, client *whatsmeow.Client, messageStore *MessageStore, chat types.JID, audio io.Reader, mimeType string) (string, error) {
+	if !reconnectSupervisor.CanSend() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+
+	oggData, err := transcodeToOggOpus(ctx, audio, mimeType)
+	if err != nil {
+		return "", fmt.Errorf("failed to transcode audio to Ogg Opus: %v", err)
+	}
+
+	duration, waveform, err := analyzeOggOpus(oggData)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze transcoded audio: %v", err)
+	}
+
+	resp, err := client.Upload(ctx, oggData, whatsmeow.MediaAudio)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload voice note: %v", err)
+	}
+
+	msg := &waProto.Message{
+		AudioMessage: &waProto.AudioMessage{
+			Mimetype:      proto.String("audio/ogg; codecs=opus"),
+			URL:           &resp.URL,
+			DirectPath:    &resp.DirectPath,
+			MediaKey:      resp.MediaKey,
+			FileEncSHA256: resp.FileEncSHA256,
+			FileSHA256:    resp.FileSHA256,
+			FileLength:    &resp.FileLength,
+			Seconds:       proto.Uint32(duration),
+			PTT:           proto.Bool(true),
+			Waveform:      waveform,
+		},
+	}
+
+	sendResp, err := client.SendMessage(ctx, chat, msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to send voice note: %v", err)
+	}
+
+	messageID := string(sendResp.ID)
+	filename := "audio_" + time.Now().Format("20060102_150405") + ".ogg"
+	if err := messageStore.StoreMessage(
+		messageID, chat.String(), client.Store.ID.User, "", sendResp.Timestamp, true,
+		"audio", filename, resp.URL, resp.MediaKey, resp.FileSHA256, resp.FileEncSHA256, resp.FileLength,
+	); err != nil {
+		return messageID, fmt.Errorf("voice note sent but failed to store locally: %v", err)
+	}
+
+	return messageID, nil
+}
+
+// sendVoiceNoteRequest is the JSON payload accepted by POST /api/send/voice.
+type sendVoiceNoteRequest struct {
+	ChatJID   string `json:"chat_jid"`
+	MediaPath string `json:"media_path"`
+	MimeType  string `json:"mime_type,omitempty"`
+}
+
+// registerVoiceNoteHandler exposes POST /api/send/voice, reading the audio file at
+// media_path from disk (matching /api/send's media_path convention) and running it
+// through SendVoiceNote.
+func registerVoiceNoteHandler(client *whatsmeow.Client, messageStore *MessageStore, logger waLog.Logger) {
+	http.HandleFunc("/api/send/voice", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkAPIKey(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		var req sendVoiceNoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid request format"})
+			return
+		}
+
+		chatJID, err := types.ParseJID(req.ChatJID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid chat JID %q: %v", req.ChatJID, err)})
+			return
+		}
+
+		file, err := os.Open(req.MediaPath)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("failed to open media file: %v", err)})
+			return
+		}
+		defer file.Close()
+
+		mimeType := req.MimeType
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+
+		messageID, err := SendVoiceNote(r.Context(), client, messageStore, chatJID, file, mimeType)
+		if err != nil {
+			logger.Warnf("Failed to send voice note to %s: %v", chatJID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"success": "true", "message_id": messageID})
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The bridge is consumed by integrators on arbitrary origins (Home Assistant, n8n, ...),
+	// and access is already gated by the API key, so origin checking is not useful here.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClient is a single WebSocket subscriber, optionally filtered to a chat JID and/or
+// a set of event kinds.
+type wsClient struct {
+	conn    *websocket.Conn
+	chatJID string
+	kinds   map[string]bool
+	send    chan EventEnvelope
+}
+
+func (c *wsClient) matches(evt EventEnvelope) bool {
+	if len(c.kinds) > 0 && !c.kinds[evt.Kind] {
+		return false
+	}
+	if c.chatJID == "" {
+		return true
+	}
+	// Envelopes whose data carries a chat JID (messages, edits, reactions, receipts, chat-state
+	// presence, transcriptions, groups) are filtered by it; anything else (e.g. connection
+	// state, contact online/offline presence) is always delivered since it isn't chat-scoped.
+	switch data := evt.Data.(type) {
+	case RecentMessage:
+		return data.ChatJID == c.chatJID
+	case map[string]interface{}:
+		if jid, ok := data["chat_jid"].(string); ok {
+			return jid == c.chatJID
+		}
+	case GroupMetadata:
+		return data.JID == c.chatJID
+	}
+	return true
+}
+
+// wsHub multiplexes the single EventBus subscription out to every connected WebSocket
+// client, so N clients can stream events without each opening its own bus subscription.
+type wsHub struct {
+	logger waLog.Logger
+
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+}
+
+func newWSHub(bus *EventBus, logger waLog.Logger) *wsHub {
+	hub := &wsHub{logger: logger, clients: make(map[*wsClient]bool)}
+
+	events, _ := bus.Subscribe()
+	go func() {
+		for evt := range events {
+			hub.broadcast(evt)
+		}
+	}()
+
+	return hub
+}
+
+func (h *wsHub) register(c *wsClient) {
+	h.mu.Lock()
+	h.clients[c] = true
+	h.mu.Unlock()
+}
+
+func (h *wsHub) unregister(c *wsClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+	close(c.send)
+}
+
+func (h *wsHub) broadcast(evt EventEnvelope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if !c.matches(evt) {
+			continue
+		}
+		select {
+		case c.send <- evt:
+		default:
+			// Slow client; drop the event rather than block the hub.
+		}
+	}
+}
+
+// registerWebSocketHandler exposes GET /api/ws/events, upgrading to a WebSocket and
+// streaming the same event envelopes the SSE/webhook subsystem produces. Clients can
+// filter by chat_jid and/or a comma-separated "kinds" query param.
+func registerWebSocketHandler(hub *wsHub, logger waLog.Logger) {
+	http.HandleFunc("/api/ws/events", func(w http.ResponseWriter, r *http.Request) {
+		if !checkAPIKey(w, r) {
+			return
+		}
+
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Warnf("WebSocket upgrade failed: %v", err)
+			return
+		}
+
+		client := &wsClient{
+			conn:    conn,
+			chatJID: r.URL.Query().Get("chat_jid"),
+			send:    make(chan EventEnvelope, 32),
+		}
+		if kindsParam := r.URL.Query().Get("kinds"); kindsParam != "" {
+			client.kinds = make(map[string]bool)
+			for _, kind := range strings.Split(kindsParam, ",") {
+				client.kinds[strings.TrimSpace(kind)] = true
+			}
+		}
+
+		hub.register(client)
+		go client.writeLoop(hub)
+		client.readLoop(hub)
+	})
+}
+
+func (c *wsClient) writeLoop(hub *wsHub) {
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+	defer c.conn.Close()
+
+	for {
+		select {
+		case evt, ok := <-c.send:
+			if !ok {
+				return
+			}
+			if err := c.conn.WriteJSON(evt); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop just drains incoming frames to detect disconnects; this endpoint is
+// publish-only from the bridge's side.
+func (c *wsClient) readLoop(hub *wsHub) {
+	defer hub.unregister(c)
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+